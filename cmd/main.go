@@ -3,13 +3,15 @@ package main
 import (
 	"context"
 	"errors"
-	"log"
+	"flag"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"github.com/RyabovNick/go-persista/internal/config"
 	"github.com/RyabovNick/go-persista/internal/metrics"
 	"github.com/RyabovNick/go-persista/internal/server"
 	"github.com/RyabovNick/go-persista/internal/storage"
@@ -17,20 +19,68 @@ import (
 )
 
 func main() {
+	configPath := flag.String("config", "", "path to a YAML/JSON config file; watched for changes if set")
+	flag.Parse()
+
+	level := new(slog.LevelVar)
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+
+	cfg := &config.Config{
+		ListenAddr:      ":8080",
+		Format:          string(storage.JSONFormat),
+		PersistInterval: 15 * time.Second,
+	}
+	if *configPath != "" {
+		loaded, err := config.Load(*configPath)
+		if err != nil {
+			logger.Error("config load error", "path", *configPath, "err", err)
+		} else {
+			cfg = loaded
+		}
+	}
+	level.Set(cfg.Level())
+
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 
-	st := storage.New(ctx, storage.WithPersistent(storage.Persistent{
-		Format:   storage.JSONFormat,
-		Interval: 15 * time.Second,
-	}))
+	opts := []storage.Option{
+		storage.WithPersistent(storage.Persistent{
+			Format:   storage.Format(cfg.Format),
+			Interval: cfg.PersistInterval,
+		}),
+		storage.WithLogger(logger),
+		storage.WithLevel(level),
+	}
+	if cfg.JanitorInterval > 0 {
+		opts = append(opts, storage.WithJanitor(storage.Janitor{Interval: cfg.JanitorInterval}))
+	}
+	if cfg.WALDir != "" {
+		opts = append(opts, storage.WithWAL(storage.WAL{
+			Dir:          cfg.WALDir,
+			SyncPolicy:   storage.SyncPolicy(cfg.WALSyncPolicy),
+			SyncInterval: cfg.WALSyncInterval,
+			SegmentSize:  cfg.WALSegmentSize,
+		}))
+	}
+
+	st := storage.New(ctx, opts...)
+
+	if *configPath != "" {
+		watcher, err := config.NewWatcher(*configPath, logger)
+		if err != nil {
+			logger.Error("config watch error", "path", *configPath, "err", err)
+		} else {
+			go watchConfig(ctx, watcher, cfg, st, logger)
+		}
+	}
 
 	srv := &server.Server{
 		Storage: st,
+		Logger:  logger,
 	}
 
 	mux := http.NewServeMux()
 
-	mux.Handle("/objects/", metrics.RequestDurationMiddleware(http.HandlerFunc(srv.HandleObject)))
+	mux.Handle("/objects/", server.RequestIDMiddleware(logger, metrics.RequestDurationMiddleware(http.HandlerFunc(srv.HandleObject))))
 
 	// Assume that the service isn't available directly from the internet
 	// Otherwise, this methods should be serve on different ports
@@ -39,7 +89,7 @@ func main() {
 	mux.Handle("/metrics", promhttp.Handler())
 
 	httpSrv := http.Server{
-		Addr:        ":8080",
+		Addr:        cfg.ListenAddr,
 		Handler:     mux,
 		ReadTimeout: 30 * time.Second,
 	}
@@ -47,9 +97,9 @@ func main() {
 	go func() {
 		defer stop()
 
-		log.Printf("server started %s", httpSrv.Addr)
+		logger.Info("server started", "addr", httpSrv.Addr)
 		if err := httpSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			log.Printf("listen and server error: %v", err)
+			logger.Error("listen and serve error", "err", err)
 		}
 	}()
 
@@ -60,11 +110,41 @@ func main() {
 	defer cancel()
 
 	if err := httpSrv.Shutdown(ctxt); err != nil {
-		log.Printf("server shutdown error: %v", err)
+		logger.Error("server shutdown error", "err", err)
 	}
-	log.Print("server stopped")
+	logger.Info("server stopped")
 
 	st.Shutdown()
 
-	log.Print("storage stopped")
+	logger.Info("storage stopped")
+}
+
+// watchConfig applies every reload of cfg's file to the running storage,
+// warning instead of applying anything that needs a restart, and
+// reporting each attempt through the persista_config_reloads_total and
+// persista_config_last_reload_success_timestamp_seconds metrics.
+func watchConfig(ctx context.Context, watcher *config.Watcher, current *config.Config, st *storage.Storage, logger *slog.Logger) {
+	watcher.Watch(ctx, func(cfg *config.Config, err error) {
+		if err != nil {
+			metrics.ConfigReloadsTotal.WithLabelValues("failure").Inc()
+			logger.Error("config reload error", "err", err)
+			return
+		}
+
+		if unsafe := current.UnsafeDiff(cfg); len(unsafe) > 0 {
+			logger.Warn("config reload: restart required to apply these fields", "fields", unsafe)
+		}
+
+		st.Reconfigure(storage.ReconfigurableConfig{
+			JanitorInterval: cfg.JanitorInterval,
+			PersistInterval: cfg.PersistInterval,
+			LogLevel:        cfg.Level(),
+		})
+
+		*current = *cfg
+
+		metrics.ConfigReloadsTotal.WithLabelValues("success").Inc()
+		metrics.ConfigLastReloadSuccessTimestamp.Set(float64(time.Now().Unix()))
+		logger.Info("config reloaded", "janitor_interval", cfg.JanitorInterval, "persist_interval", cfg.PersistInterval, "log_level", cfg.LogLevel)
+	})
 }
@@ -0,0 +1,147 @@
+// Package config loads go-persista's runtime configuration from a
+// YAML/JSON file and watches it for changes, so a safe subset of
+// settings can be reconfigured without a restart.
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is go-persista's runtime configuration.
+//
+// JanitorInterval, PersistInterval and LogLevel can be changed on a
+// running server by editing the file - see Watcher. ListenAddr, Format
+// and the WAL* fields take effect only on startup; changing them in a
+// running config file is logged as a warning and otherwise ignored.
+//
+// There's deliberately no Backend field yet: picking one at runtime
+// needs backend-specific connection settings (bucket, region, container,
+// ...) that aren't here, and a field that can't actually select a
+// backend would silently do nothing. Add it once those settings exist.
+type Config struct {
+	// ListenAddr is the address the HTTP server listens on.
+	ListenAddr string `yaml:"listen_addr" json:"listen_addr"`
+
+	// Format is the snapshot encoding: "json" or "gob".
+	Format string `yaml:"format" json:"format"`
+
+	// JanitorInterval is how often expired objects are swept.
+	//
+	// Default interval is 15 seconds.
+	JanitorInterval time.Duration `yaml:"janitor_interval" json:"janitor_interval"`
+
+	// PersistInterval is how often the storage is snapshotted.
+	//
+	// Default interval is 15 seconds.
+	PersistInterval time.Duration `yaml:"persist_interval" json:"persist_interval"`
+
+	// LogLevel is the slog level: "debug", "info", "warn" or "error".
+	//
+	// Info is used by default.
+	LogLevel string `yaml:"log_level" json:"log_level"`
+
+	// WALDir enables the write-ahead log by setting the directory its
+	// segments are stored in. Left empty, no WAL is used and Puts between
+	// two persistence ticks aren't crash-safe.
+	WALDir string `yaml:"wal_dir" json:"wal_dir"`
+
+	// WALSyncPolicy controls how often WAL segments are fsynced:
+	// "always", "interval" or "never". Only used when WALDir is set.
+	//
+	// "always" is used by default.
+	WALSyncPolicy string `yaml:"wal_sync_policy" json:"wal_sync_policy"`
+
+	// WALSyncInterval is the fsync period used with WALSyncPolicy
+	// "interval".
+	//
+	// Default interval is 1 second.
+	WALSyncInterval time.Duration `yaml:"wal_sync_interval" json:"wal_sync_interval"`
+
+	// WALSegmentSize is the maximum size, in bytes, a single WAL segment
+	// is allowed to grow to before it's rotated into a new one.
+	//
+	// Default size is 64MiB.
+	WALSegmentSize int64 `yaml:"wal_segment_size" json:"wal_segment_size"`
+}
+
+// Load reads and parses a Config from path. The format is chosen by the
+// file extension: ".json" for JSON, anything else for YAML.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config error: %w", err)
+	}
+
+	cfg := Config{
+		JanitorInterval: 15 * time.Second,
+		PersistInterval: 15 * time.Second,
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return nil, fmt.Errorf("json decode config error: %w", err)
+		}
+	} else {
+		if err := yaml.Unmarshal(data, &cfg); err != nil {
+			return nil, fmt.Errorf("yaml decode config error: %w", err)
+		}
+	}
+
+	return &cfg, nil
+}
+
+// Level parses c.LogLevel into a slog.Level, defaulting to slog.LevelInfo
+// for an empty or unrecognized value.
+func (c *Config) Level() slog.Level {
+	switch strings.ToLower(c.LogLevel) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// UnsafeDiff reports the restart-only fields that differ between c and
+// other, by name, so a reload can warn about the ones it can't apply
+// live instead of silently ignoring them.
+func (c *Config) UnsafeDiff(other *Config) []string {
+	var diff []string
+
+	if c.ListenAddr != other.ListenAddr {
+		diff = append(diff, "listen_addr")
+	}
+
+	if c.Format != other.Format {
+		diff = append(diff, "format")
+	}
+
+	if c.WALDir != other.WALDir {
+		diff = append(diff, "wal_dir")
+	}
+
+	if c.WALSyncPolicy != other.WALSyncPolicy {
+		diff = append(diff, "wal_sync_policy")
+	}
+
+	if c.WALSyncInterval != other.WALSyncInterval {
+		diff = append(diff, "wal_sync_interval")
+	}
+
+	if c.WALSegmentSize != other.WALSegmentSize {
+		diff = append(diff, "wal_segment_size")
+	}
+
+	return diff
+}
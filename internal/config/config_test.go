@@ -0,0 +1,74 @@
+package config
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadYAML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte(`
+listen_addr: ":9090"
+format: gob
+janitor_interval: 30s
+persist_interval: 1m
+log_level: debug
+wal_dir: /var/lib/persista/wal
+wal_sync_policy: interval
+wal_sync_interval: 2s
+wal_segment_size: 1048576
+`), 0o600)
+	require.NoError(t, err)
+
+	cfg, err := Load(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, ":9090", cfg.ListenAddr)
+	assert.Equal(t, "gob", cfg.Format)
+	assert.Equal(t, 30*time.Second, cfg.JanitorInterval)
+	assert.Equal(t, time.Minute, cfg.PersistInterval)
+	assert.Equal(t, slog.LevelDebug, cfg.Level())
+	assert.Equal(t, "/var/lib/persista/wal", cfg.WALDir)
+	assert.Equal(t, "interval", cfg.WALSyncPolicy)
+	assert.Equal(t, 2*time.Second, cfg.WALSyncInterval)
+	assert.Equal(t, int64(1048576), cfg.WALSegmentSize)
+}
+
+func TestLoadJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	err := os.WriteFile(path, []byte(`{"listen_addr": ":8081", "log_level": "warn"}`), 0o600)
+	require.NoError(t, err)
+
+	cfg, err := Load(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, ":8081", cfg.ListenAddr)
+	assert.Equal(t, slog.LevelWarn, cfg.Level())
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	_, err := Load(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err)
+}
+
+func TestConfigLevelDefaultsToInfo(t *testing.T) {
+	cfg := &Config{}
+	assert.Equal(t, slog.LevelInfo, cfg.Level())
+
+	cfg.LogLevel = "unknown"
+	assert.Equal(t, slog.LevelInfo, cfg.Level())
+}
+
+func TestConfigUnsafeDiff(t *testing.T) {
+	a := &Config{ListenAddr: ":8080", Format: "json", WALDir: "wal-a", WALSyncPolicy: "always", WALSyncInterval: time.Second, WALSegmentSize: 1024}
+	b := &Config{ListenAddr: ":8081", Format: "gob", WALDir: "wal-b", WALSyncPolicy: "never", WALSyncInterval: 2 * time.Second, WALSegmentSize: 2048}
+
+	assert.ElementsMatch(t, []string{"listen_addr", "format", "wal_dir", "wal_sync_policy", "wal_sync_interval", "wal_segment_size"}, a.UnsafeDiff(b))
+	assert.Empty(t, a.UnsafeDiff(a))
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Watcher watches a config file for changes and reparses it on every
+// write, re-arming the watch on rename/remove events - the pattern
+// editors that write a new file and move it into place require, and the
+// same one Prometheus's statsd_exporter uses for its own watchConfig
+// loop.
+type Watcher struct {
+	watcher *fsnotify.Watcher
+	path    string
+	logger  *slog.Logger
+}
+
+// NewWatcher creates a Watcher for path. Call Watch to start it.
+func NewWatcher(path string, logger *slog.Logger) (*Watcher, error) {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("fsnotify watcher error: %w", err)
+	}
+
+	// The containing directory, not the file itself, is watched so a
+	// rename-over-the-original save still leaves the watch in place.
+	if err := w.Add(filepath.Dir(path)); err != nil {
+		w.Close()
+		return nil, fmt.Errorf("fsnotify watch error: %w", err)
+	}
+
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return &Watcher{watcher: w, path: path, logger: logger}, nil
+}
+
+// Watch blocks, calling onReload with the freshly loaded Config every
+// time the watched file changes, until ctx is done. onReload is also
+// called with a non-nil error and a nil Config if the file can't be
+// read or parsed, so the caller can count the failed reload without
+// applying a partial config.
+func (w *Watcher) Watch(ctx context.Context, onReload func(*Config, error)) {
+	defer w.watcher.Close()
+
+	abs, err := filepath.Abs(w.path)
+	if err != nil {
+		abs = w.path
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+
+			eventAbs, err := filepath.Abs(event.Name)
+			if err != nil {
+				eventAbs = event.Name
+			}
+
+			if eventAbs != abs {
+				continue
+			}
+
+			if event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+				// The watched name itself is gone - re-add the
+				// directory watch so its replacement is still seen.
+				if err := w.watcher.Add(filepath.Dir(w.path)); err != nil {
+					w.logger.Error("config re-watch error", "err", err)
+				}
+			}
+
+			cfg, err := Load(w.path)
+			onReload(cfg, err)
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+
+			w.logger.Error("config watch error", "err", err)
+		}
+	}
+}
@@ -1,43 +1,155 @@
-// Package metrics provides prometheus metrics
-package metrics
-
-import (
-	"net/http"
-	"path"
-
-	"github.com/prometheus/client_golang/prometheus"
-)
-
-var (
-	// PutObjectsCounter is a counter of objects put in the storage
-	PutObjectsCounter = prometheus.NewCounter(prometheus.CounterOpts{
-		Name: "put_objects_total",
-		Help: "The total number of objects put in the storage",
-	})
-
-	// GetObjectsCounter is a counter of objects get from the storage
-	GetObjectsCounter = prometheus.NewCounter(prometheus.CounterOpts{
-		Name: "get_objects_total",
-		Help: "The total number of objects get from the storage",
-	})
-
-	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
-		Name: "request_duration_seconds",
-		Help: "The duration of HTTP requests",
-	}, []string{"method", "path"})
-)
-
-// RequestDurationMiddleware is a middleware that measures the duration
-func RequestDurationMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		timer := prometheus.NewTimer(requestDuration.WithLabelValues(r.Method, path.Dir(r.URL.Path)))
-		next.ServeHTTP(w, r)
-		timer.ObserveDuration()
-	})
-}
-
-func init() {
-	prometheus.MustRegister(PutObjectsCounter)
-	prometheus.MustRegister(GetObjectsCounter)
-	prometheus.MustRegister(requestDuration)
-}
+// Package metrics provides prometheus metrics
+package metrics
+
+import (
+	"net/http"
+	"path"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// PutObjectsCounter is a counter of objects put in the storage
+	PutObjectsCounter = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "put_objects_total",
+		Help: "The total number of objects put in the storage",
+	})
+
+	// GetObjectsCounter is a counter of objects get from the storage
+	GetObjectsCounter = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "get_objects_total",
+		Help: "The total number of objects get from the storage",
+	})
+
+	// DeleteObjectsCounter is a counter of objects deleted from the storage
+	DeleteObjectsCounter = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "delete_objects_total",
+		Help: "The total number of objects deleted from the storage",
+	})
+
+	// requestDuration tracks how long HTTP requests take, broken down by
+	// method, route and the status code the response was written with.
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "request_duration_seconds",
+		Help:    "The duration of HTTP requests",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// responseSize tracks the size of HTTP response bodies, so operators
+	// can tell a slow request from a merely large one.
+	responseSize = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "response_size_bytes",
+		Help:    "The size of HTTP response bodies",
+		Buckets: prometheus.ExponentialBuckets(64, 4, 8),
+	}, []string{"method", "route"})
+
+	// requestsInFlight tracks how many HTTP requests are currently being
+	// served, so a stuck handler shows up before its requests start
+	// timing out.
+	requestsInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "requests_in_flight",
+		Help: "The number of HTTP requests currently being served",
+	})
+
+	// StorageObjects is the current number of objects held in the
+	// storage.
+	StorageObjects = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "persista_objects",
+		Help: "The current number of objects held in the storage",
+	})
+
+	// StorageBytes is the current sum of len(Data) across every object
+	// held in the storage.
+	StorageBytes = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "persista_bytes",
+		Help: "The current sum of object data sizes held in the storage, in bytes",
+	})
+
+	// LastSaveTimestamp is the unix timestamp of the last successful
+	// snapshot save, so operators can alert on stale snapshots.
+	LastSaveTimestamp = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "persista_last_save_timestamp_seconds",
+		Help: "Unix timestamp of the last successful snapshot save",
+	})
+
+	// SaveDuration tracks how long snapshot saves take.
+	SaveDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "persista_save_duration_seconds",
+		Help:    "The duration of storage snapshot saves",
+		Buckets: prometheus.DefBuckets,
+	})
+
+	// ConfigReloadsTotal counts config file reloads, labeled by whether
+	// the reload could be parsed and applied.
+	ConfigReloadsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "persista_config_reloads_total",
+		Help: "The total number of configuration reloads, by result",
+	}, []string{"result"})
+
+	// ConfigLastReloadSuccessTimestamp is the unix timestamp of the last
+	// successful configuration reload, so a stuck or failing reload loop
+	// is alertable.
+	ConfigLastReloadSuccessTimestamp = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "persista_config_last_reload_success_timestamp_seconds",
+		Help: "Unix timestamp of the last successful configuration reload",
+	})
+)
+
+// statusRecorder wraps http.ResponseWriter to capture the status code
+// and byte count a handler wrote, since net/http doesn't expose either
+// after the fact.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += n
+
+	return n, err
+}
+
+// RequestDurationMiddleware is a middleware that instruments every
+// request with its duration, response size and status code, and tracks
+// the number of requests currently in flight.
+func RequestDurationMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestsInFlight.Inc()
+		defer requestsInFlight.Dec()
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		route := path.Dir(r.URL.Path)
+
+		timer := prometheus.NewTimer(prometheus.ObserverFunc(func(seconds float64) {
+			requestDuration.WithLabelValues(r.Method, route, strconv.Itoa(rec.status)).Observe(seconds)
+		}))
+		defer timer.ObserveDuration()
+
+		next.ServeHTTP(rec, r)
+
+		responseSize.WithLabelValues(r.Method, route).Observe(float64(rec.bytes))
+	})
+}
+
+func init() {
+	prometheus.MustRegister(PutObjectsCounter)
+	prometheus.MustRegister(GetObjectsCounter)
+	prometheus.MustRegister(DeleteObjectsCounter)
+	prometheus.MustRegister(requestDuration)
+	prometheus.MustRegister(responseSize)
+	prometheus.MustRegister(requestsInFlight)
+	prometheus.MustRegister(StorageObjects)
+	prometheus.MustRegister(StorageBytes)
+	prometheus.MustRegister(LastSaveTimestamp)
+	prometheus.MustRegister(SaveDuration)
+	prometheus.MustRegister(ConfigReloadsTotal)
+	prometheus.MustRegister(ConfigLastReloadSuccessTimestamp)
+}
@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatusRecorderDefaultsToOK(t *testing.T) {
+	rec := &statusRecorder{ResponseWriter: httptest.NewRecorder(), status: http.StatusOK}
+
+	n, err := rec.Write([]byte("hello"))
+	require.NoError(t, err)
+
+	require.Equal(t, 5, n)
+	require.Equal(t, http.StatusOK, rec.status)
+	require.Equal(t, 5, rec.bytes)
+}
+
+func TestStatusRecorderCapturesWrittenStatus(t *testing.T) {
+	rec := &statusRecorder{ResponseWriter: httptest.NewRecorder(), status: http.StatusOK}
+
+	rec.WriteHeader(http.StatusTeapot)
+	_, _ = rec.Write([]byte("abc"))
+	_, _ = rec.Write([]byte("de"))
+
+	require.Equal(t, http.StatusTeapot, rec.status)
+	require.Equal(t, 5, rec.bytes)
+}
+
+func TestRequestDurationMiddlewareTracksInFlight(t *testing.T) {
+	inFlightDuringHandler := -1.0
+
+	handler := RequestDurationMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		inFlightDuringHandler = testutil.ToFloat64(requestsInFlight)
+	}))
+
+	before := testutil.ToFloat64(requestsInFlight)
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/objects/a", nil))
+
+	require.Equal(t, before+1, inFlightDuringHandler)
+	require.Equal(t, before, testutil.ToFloat64(requestsInFlight))
+}
+
+func TestRequestDurationMiddlewareObservesStatusAndSize(t *testing.T) {
+	handler := RequestDurationMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("0123456789"))
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/objects/a", nil))
+
+	var durationPB dto.Metric
+	require.NoError(t, requestDuration.WithLabelValues(http.MethodPost, "/objects", "201").(prometheus.Metric).Write(&durationPB))
+	require.EqualValues(t, 1, durationPB.GetHistogram().GetSampleCount())
+
+	var sizePB dto.Metric
+	require.NoError(t, responseSize.WithLabelValues(http.MethodPost, "/objects").(prometheus.Metric).Write(&sizePB))
+	require.EqualValues(t, 1, sizePB.GetHistogram().GetSampleCount())
+	require.EqualValues(t, 10, sizePB.GetHistogram().GetSampleSum())
+}
@@ -0,0 +1,53 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+)
+
+// RequestIDHeader is the header a correlation ID is read from and
+// echoed back on, so a caller can tie its own logs to the server's.
+const RequestIDHeader = "X-Request-Id"
+
+type loggerContextKey struct{}
+
+// RequestIDMiddleware reuses the X-Request-Id header from the caller if
+// present, otherwise generates one, and injects a child of logger
+// tagged with it into the request context - so handlers can log
+// per-request without threading the ID through every call.
+func RequestIDMiddleware(logger *slog.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(RequestIDHeader)
+		if requestID == "" {
+			requestID = newRequestID()
+		}
+
+		w.Header().Set(RequestIDHeader, requestID)
+
+		ctx := context.WithValue(r.Context(), loggerContextKey{}, logger.With("request_id", requestID))
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// newRequestID returns a random hex-encoded correlation ID.
+func newRequestID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+
+	return hex.EncodeToString(b[:])
+}
+
+// loggerFromContext returns the per-request logger RequestIDMiddleware
+// injected, or fallback if ctx carries none - e.g. in tests that call a
+// handler directly without going through the middleware.
+func loggerFromContext(ctx context.Context, fallback *slog.Logger) *slog.Logger {
+	if logger, ok := ctx.Value(loggerContextKey{}).(*slog.Logger); ok {
+		return logger
+	}
+
+	return fallback
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestIDMiddlewareGeneratesIDWhenMissing(t *testing.T) {
+	var gotLogger *slog.Logger
+
+	handler := RequestIDMiddleware(slog.Default(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotLogger = loggerFromContext(r.Context(), nil)
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/objects/a", nil))
+
+	id := w.Header().Get(RequestIDHeader)
+	assert.NotEmpty(t, id)
+	require.NotNil(t, gotLogger)
+}
+
+func TestRequestIDMiddlewareReusesIncomingID(t *testing.T) {
+	handler := RequestIDMiddleware(slog.Default(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	r := httptest.NewRequest(http.MethodGet, "/objects/a", nil)
+	r.Header.Set(RequestIDHeader, "caller-supplied-id")
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	assert.Equal(t, "caller-supplied-id", w.Header().Get(RequestIDHeader))
+}
+
+func TestRequestIDMiddlewareTagsLoggerWithID(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+
+	handler := RequestIDMiddleware(logger, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		loggerFromContext(r.Context(), logger).Info("handled")
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/objects/a", nil)
+	r.Header.Set(RequestIDHeader, "req-123")
+
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	assert.Contains(t, buf.String(), "request_id=req-123")
+}
+
+func TestLoggerFromContextFallsBackWithoutMiddleware(t *testing.T) {
+	fallback := slog.Default()
+
+	r := httptest.NewRequest(http.MethodGet, "/objects/a", nil)
+
+	assert.Same(t, fallback, loggerFromContext(r.Context(), fallback))
+}
@@ -1,8 +1,11 @@
 package server
 
 import (
+	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -11,11 +14,29 @@ import (
 )
 
 const (
-	ExpiresHeader = "Expires"
+	ExpiresHeader     = "Expires"
+	ETagHeader        = "ETag"
+	IfMatchHeader     = "If-Match"
+	IfNoneMatchHeader = "If-None-Match"
 )
 
 type Server struct {
 	Storage *storage.Storage
+
+	// Logger is the fallback logger handlers use when a request didn't
+	// go through RequestIDMiddleware.
+	//
+	// slog.Default() is used if this field is nil.
+	Logger *slog.Logger
+}
+
+// logger returns s.Logger, or slog.Default() if it's nil.
+func (s *Server) logger() *slog.Logger {
+	if s.Logger != nil {
+		return s.Logger
+	}
+
+	return slog.Default()
 }
 
 func (s *Server) HandleObject(w http.ResponseWriter, r *http.Request) {
@@ -32,12 +53,17 @@ func (s *Server) HandleObject(w http.ResponseWriter, r *http.Request) {
 	case "PUT":
 		metrics.PutObjectsCounter.Inc()
 		s.putObjectHandler(w, r, key)
+	case "DELETE":
+		metrics.DeleteObjectsCounter.Inc()
+		s.deleteObjectHandler(w, r, key)
 	default:
 		http.Error(w, "Unsupported method", http.StatusMethodNotAllowed)
 	}
 }
 
 func (s *Server) putObjectHandler(w http.ResponseWriter, r *http.Request, key string) {
+	logger := loggerFromContext(r.Context(), s.logger())
+
 	// convert expires header to time if exists
 	var (
 		expires *time.Time
@@ -59,24 +85,114 @@ func (s *Server) putObjectHandler(w http.ResponseWriter, r *http.Request, key st
 	}
 	defer r.Body.Close()
 
-	// Handle PUT /objects/{Key}
-	s.Storage.Put(key, data, expires)
+	var rev uint64
+
+	switch {
+	case r.Header.Get(IfNoneMatchHeader) == "*":
+		var ok bool
+		rev, ok, err = s.Storage.CompareAndSwap(key, 0, data, expires)
+		if err == nil && !ok {
+			logger.Info("put object", "key", key, "bytes", len(data), "outcome", "precondition_failed")
+			http.Error(w, "Precondition failed", http.StatusPreconditionFailed)
+			return
+		}
+	case r.Header.Get(IfMatchHeader) != "":
+		expectedRev, parseErr := parseETag(r.Header.Get(IfMatchHeader))
+		if parseErr != nil {
+			http.Error(w, "Invalid If-Match header", http.StatusBadRequest)
+			return
+		}
+
+		var ok bool
+		rev, ok, err = s.Storage.CompareAndSwap(key, expectedRev, data, expires)
+		if err == nil && !ok {
+			logger.Info("put object", "key", key, "bytes", len(data), "outcome", "precondition_failed")
+			http.Error(w, "Precondition failed", http.StatusPreconditionFailed)
+			return
+		}
+	default:
+		// Handle PUT /objects/{Key}
+		rev, err = s.Storage.Put(key, data, expires)
+	}
+
+	if err != nil {
+		logger.Error("put object", "key", key, "bytes", len(data), "outcome", "storage_error", "err", err)
+		http.Error(w, "Storage write error", http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info("put object", "key", key, "bytes", len(data), "rev", rev, "outcome", "ok")
 
+	w.Header().Set(ETagHeader, formatETag(rev))
 	w.WriteHeader(http.StatusOK)
 }
 
-func (s *Server) getObjectHandler(w http.ResponseWriter, _ *http.Request, key string) {
-	data, ok := s.Storage.Get(key)
+func (s *Server) getObjectHandler(w http.ResponseWriter, r *http.Request, key string) {
+	logger := loggerFromContext(r.Context(), s.logger())
+
+	data, rev, ok := s.Storage.GetWithRev(key)
 	if !ok {
+		logger.Info("get object", "key", key, "outcome", "not_found")
 		http.Error(w, "Object not found", http.StatusNotFound)
 		return
 	}
 
+	logger.Info("get object", "key", key, "bytes", len(data), "rev", rev, "outcome", "ok")
+
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set(ETagHeader, formatETag(rev))
 	w.WriteHeader(http.StatusOK)
 	w.Write(data)
 }
 
+func (s *Server) deleteObjectHandler(w http.ResponseWriter, r *http.Request, key string) {
+	logger := loggerFromContext(r.Context(), s.logger())
+
+	// Handle DELETE /objects/{Key}
+	if ifMatch := r.Header.Get(IfMatchHeader); ifMatch != "" {
+		expectedRev, err := parseETag(ifMatch)
+		if err != nil {
+			http.Error(w, "Invalid If-Match header", http.StatusBadRequest)
+			return
+		}
+
+		if !s.Storage.CompareAndDelete(key, expectedRev) {
+			logger.Info("delete object", "key", key, "outcome", "precondition_failed")
+			http.Error(w, "Precondition failed", http.StatusPreconditionFailed)
+			return
+		}
+
+		logger.Info("delete object", "key", key, "outcome", "ok")
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if !s.Storage.Delete(key) {
+		logger.Info("delete object", "key", key, "outcome", "not_found")
+		http.Error(w, "Object not found", http.StatusNotFound)
+		return
+	}
+
+	logger.Info("delete object", "key", key, "outcome", "ok")
+	w.WriteHeader(http.StatusOK)
+}
+
+// formatETag renders rev as a quoted HTTP entity-tag, e.g. `"42"`.
+func formatETag(rev uint64) string {
+	return fmt.Sprintf("%q", strconv.FormatUint(rev, 10))
+}
+
+// parseETag parses a quoted entity-tag produced by formatETag back into
+// a revision.
+func parseETag(etag string) (uint64, error) {
+	rev, err := strconv.ParseUint(strings.Trim(etag, `"`), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse etag error: %w", err)
+	}
+
+	return rev, nil
+}
+
 func (s *Server) HandleLivenessProbe(w http.ResponseWriter, _ *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/RyabovNick/go-persista/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestServer() *Server {
+	return &Server{Storage: storage.New(context.Background())}
+}
+
+func putRequest(key, body string, headers map[string]string) *http.Request {
+	r := httptest.NewRequest(http.MethodPut, "/objects/"+key, strings.NewReader(body))
+	for k, v := range headers {
+		r.Header.Set(k, v)
+	}
+
+	return r
+}
+
+func TestHandleObjectPutAndGet(t *testing.T) {
+	srv := newTestServer()
+
+	w := httptest.NewRecorder()
+	srv.HandleObject(w, putRequest("a", `{"k":"v"}`, nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	etag := w.Header().Get(ETagHeader)
+	assert.NotEmpty(t, etag)
+
+	w = httptest.NewRecorder()
+	srv.HandleObject(w, httptest.NewRequest(http.MethodGet, "/objects/a", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"k":"v"}`, w.Body.String())
+	assert.Equal(t, etag, w.Header().Get(ETagHeader))
+}
+
+func TestHandleObjectGetMissing(t *testing.T) {
+	srv := newTestServer()
+
+	w := httptest.NewRecorder()
+	srv.HandleObject(w, httptest.NewRequest(http.MethodGet, "/objects/missing", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestHandleObjectPutIfNoneMatchRejectsExisting(t *testing.T) {
+	srv := newTestServer()
+
+	w := httptest.NewRecorder()
+	srv.HandleObject(w, putRequest("a", "1", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	srv.HandleObject(w, putRequest("a", "2", map[string]string{IfNoneMatchHeader: "*"}))
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	w = httptest.NewRecorder()
+	srv.HandleObject(w, httptest.NewRequest(http.MethodGet, "/objects/a", nil))
+	assert.Equal(t, "1", w.Body.String())
+}
+
+func TestHandleObjectPutIfMatchRejectsStaleRevision(t *testing.T) {
+	srv := newTestServer()
+
+	w := httptest.NewRecorder()
+	srv.HandleObject(w, putRequest("a", "1", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	staleETag := w.Header().Get(ETagHeader)
+
+	w = httptest.NewRecorder()
+	srv.HandleObject(w, putRequest("a", "2", map[string]string{IfMatchHeader: staleETag}))
+	require.Equal(t, http.StatusOK, w.Code)
+	freshETag := w.Header().Get(ETagHeader)
+	assert.NotEqual(t, staleETag, freshETag)
+
+	// Replaying the now-stale If-Match must be rejected.
+	w = httptest.NewRecorder()
+	srv.HandleObject(w, putRequest("a", "3", map[string]string{IfMatchHeader: staleETag}))
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+}
+
+func TestHandleObjectDeleteIfMatch(t *testing.T) {
+	srv := newTestServer()
+
+	w := httptest.NewRecorder()
+	srv.HandleObject(w, putRequest("a", "1", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	etag := w.Header().Get(ETagHeader)
+
+	// A mismatched revision is rejected without deleting.
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodDelete, "/objects/a", nil)
+	req.Header.Set(IfMatchHeader, `"999999"`)
+	srv.HandleObject(w, req)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodDelete, "/objects/a", nil)
+	req.Header.Set(IfMatchHeader, etag)
+	srv.HandleObject(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	srv.HandleObject(w, httptest.NewRequest(http.MethodGet, "/objects/a", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestHandleObjectDeleteMissing(t *testing.T) {
+	srv := newTestServer()
+
+	w := httptest.NewRecorder()
+	srv.HandleObject(w, httptest.NewRequest(http.MethodDelete, "/objects/missing", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestHandleObjectMissingKey(t *testing.T) {
+	srv := newTestServer()
+
+	w := httptest.NewRecorder()
+	srv.HandleObject(w, httptest.NewRequest(http.MethodGet, "/objects/", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestHandleObjectUnsupportedMethod(t *testing.T) {
+	srv := newTestServer()
+
+	w := httptest.NewRecorder()
+	srv.HandleObject(w, httptest.NewRequest(http.MethodPatch, "/objects/a", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
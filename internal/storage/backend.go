@@ -0,0 +1,194 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// PersistBackend abstracts where snapshots are read from and written to,
+// so Storage can be deployed against a local disk or an object store
+// (S3, GCS, OpenStack Swift) without save/load knowing the difference -
+// the same multi-backend split Cortex uses for its chunk storage.
+type PersistBackend interface {
+	// Put uploads the content read from r under name, overwriting
+	// whatever was there before. Implementations should make the
+	// upload atomic: a reader observing name mid-Put must see either
+	// the old content or the new one, never a partial write.
+	Put(ctx context.Context, name string, r io.Reader) error
+
+	// Get opens name for reading. The caller must Close the returned
+	// reader. It returns an error satisfying os.IsNotExist if name
+	// doesn't exist.
+	Get(ctx context.Context, name string) (io.ReadCloser, error)
+
+	// List returns the names of every object whose name starts with
+	// prefix, in no particular order.
+	List(ctx context.Context, prefix string) ([]string, error)
+
+	// Delete removes name. It's not an error for name to already be
+	// gone.
+	Delete(ctx context.Context, name string) error
+}
+
+// FilesystemBackend is the default PersistBackend: it stores objects as
+// files in Dir, which reproduces the storage's original on-disk
+// behavior before backends were pluggable. Names may themselves be
+// relative or absolute paths (Persistent.Name has always allowed that),
+// in which case they're used as given instead of being joined to Dir.
+type FilesystemBackend struct {
+	// Dir is the directory objects are stored in, for names that don't
+	// carry their own path.
+	//
+	// Default directory is the current working directory.
+	Dir string
+}
+
+// NewFilesystemBackend returns a FilesystemBackend rooted at dir,
+// creating it if it doesn't exist yet.
+func NewFilesystemBackend(dir string) (*FilesystemBackend, error) {
+	if dir == "" {
+		dir = "."
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create backend dir error: %w", err)
+	}
+
+	return &FilesystemBackend{Dir: dir}, nil
+}
+
+// path resolves name against Dir, unless name is already absolute or
+// carries its own directory component.
+func (b *FilesystemBackend) path(name string) string {
+	if filepath.IsAbs(name) || strings.ContainsRune(name, filepath.Separator) {
+		return name
+	}
+
+	dir := b.Dir
+	if dir == "" {
+		dir = "."
+	}
+
+	return filepath.Join(dir, name)
+}
+
+// Put writes r to a temporary file next to the resolved target and
+// renames it into place, so a reader never observes a partially written
+// object.
+func (b *FilesystemBackend) Put(_ context.Context, name string, r io.Reader) error {
+	target := b.path(name)
+
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return fmt.Errorf("create parent dir error: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(target), filepath.Base(target)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("create temp file error: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write temp file error: %w", err)
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("sync temp file error: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close temp file error: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return fmt.Errorf("rename temp file error: %w", err)
+	}
+
+	if err := syncDir(filepath.Dir(target)); err != nil {
+		return fmt.Errorf("sync parent dir error: %w", err)
+	}
+
+	return nil
+}
+
+// syncDir fsyncs dir itself, so a rename into it is durable across a
+// crash and not just reordered ahead of it in the page cache.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	if err := d.Sync(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Get opens the resolved name for reading.
+func (b *FilesystemBackend) Get(_ context.Context, name string) (io.ReadCloser, error) {
+	file, err := os.Open(b.path(name))
+	if err != nil {
+		return nil, err
+	}
+
+	return file, nil
+}
+
+// List returns names starting with prefix, scanning the directory
+// prefix resolves into. The returned names are in the same form Put
+// originally received them in - bare for names that resolved against
+// Dir, carrying their own directory component otherwise - so round-
+// tripping a name through Get/Delete, or matching it against a prefix
+// computed the same way Put's was, works either way.
+func (b *FilesystemBackend) List(_ context.Context, prefix string) ([]string, error) {
+	full := b.path(prefix)
+	dir := filepath.Dir(full)
+	base := filepath.Base(full)
+	bare := !filepath.IsAbs(prefix) && !strings.ContainsRune(prefix, filepath.Separator)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("read backend dir error: %w", err)
+	}
+
+	var out []string
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasPrefix(e.Name(), base) {
+			continue
+		}
+
+		if bare {
+			out = append(out, e.Name())
+		} else {
+			out = append(out, filepath.Join(dir, e.Name()))
+		}
+	}
+
+	sort.Strings(out)
+
+	return out, nil
+}
+
+// Delete removes the resolved name. It's not an error for it to already
+// be gone.
+func (b *FilesystemBackend) Delete(_ context.Context, name string) error {
+	if err := os.Remove(b.path(name)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/iterator"
+)
+
+// GCSBackend is a PersistBackend backed by a Google Cloud Storage
+// bucket.
+type GCSBackend struct {
+	bucket *storage.BucketHandle
+}
+
+// NewGCSBackend returns a GCSBackend that stores objects in the named
+// bucket using client. Build client with storage.NewClient, which picks
+// up application-default credentials unless overridden with options.
+func NewGCSBackend(client *storage.Client, bucket string) *GCSBackend {
+	return &GCSBackend{bucket: client.Bucket(bucket)}
+}
+
+// Put uploads r to name. GCS resumable/simple uploads are atomic: the
+// object only becomes visible once Writer.Close succeeds.
+func (b *GCSBackend) Put(ctx context.Context, name string, r io.Reader) error {
+	w := b.bucket.Object(name).NewWriter(ctx)
+
+	if _, err := io.Copy(w, r); err != nil {
+		w.Close()
+		return fmt.Errorf("gcs write object error: %w", err)
+	}
+
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("gcs close object error: %w", err)
+	}
+
+	return nil
+}
+
+// Get opens name for reading.
+func (b *GCSBackend) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	r, err := b.bucket.Object(name).NewReader(ctx)
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return nil, os.ErrNotExist
+		}
+
+		return nil, fmt.Errorf("gcs open object error: %w", err)
+	}
+
+	return r, nil
+}
+
+// List returns the object names in the bucket starting with prefix.
+func (b *GCSBackend) List(ctx context.Context, prefix string) ([]string, error) {
+	var out []string
+
+	it := b.bucket.Objects(ctx, &storage.Query{Prefix: prefix})
+	for {
+		attrs, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("gcs list objects error: %w", err)
+		}
+
+		out = append(out, attrs.Name)
+	}
+
+	return out, nil
+}
+
+// Delete removes name from the bucket. It's not an error for name to
+// already be gone.
+func (b *GCSBackend) Delete(ctx context.Context, name string) error {
+	if err := b.bucket.Object(name).Delete(ctx); err != nil && !errors.Is(err, storage.ErrObjectNotExist) {
+		return fmt.Errorf("gcs delete object error: %w", err)
+	}
+
+	return nil
+}
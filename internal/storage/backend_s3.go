@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// S3Backend is a PersistBackend backed by an S3 (or S3-compatible)
+// bucket, so the storage can run in Kubernetes without a persistent
+// volume.
+type S3Backend struct {
+	client *s3.Client
+	bucket string
+}
+
+// NewS3Backend returns an S3Backend that stores objects in bucket using
+// client. Build client with s3.NewFromConfig and an aws.Config loaded by
+// config.LoadDefaultConfig, so credentials and region come from the
+// standard AWS chain (env vars, shared config, instance profile, ...).
+func NewS3Backend(client *s3.Client, bucket string) *S3Backend {
+	return &S3Backend{client: client, bucket: bucket}
+}
+
+// Put uploads r to name. S3's PutObject is a single atomic request, so
+// readers never observe a partial object.
+func (b *S3Backend) Put(ctx context.Context, name string, r io.Reader) error {
+	_, err := b.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(name),
+		Body:   r,
+	})
+	if err != nil {
+		return fmt.Errorf("s3 put object error: %w", err)
+	}
+
+	return nil
+}
+
+// Get opens name for reading.
+func (b *S3Backend) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	out, err := b.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(name),
+	})
+	if err != nil {
+		var nsk *types.NoSuchKey
+		if errors.As(err, &nsk) {
+			return nil, os.ErrNotExist
+		}
+
+		return nil, fmt.Errorf("s3 get object error: %w", err)
+	}
+
+	return out.Body, nil
+}
+
+// List returns the keys in bucket starting with prefix, paginating
+// through ListObjectsV2 as needed.
+func (b *S3Backend) List(ctx context.Context, prefix string) ([]string, error) {
+	var (
+		out   []string
+		token *string
+	)
+
+	for {
+		page, err := b.client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+			Bucket:            aws.String(b.bucket),
+			Prefix:            aws.String(prefix),
+			ContinuationToken: token,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("s3 list objects error: %w", err)
+		}
+
+		for _, obj := range page.Contents {
+			out = append(out, aws.ToString(obj.Key))
+		}
+
+		if page.NextContinuationToken == nil {
+			break
+		}
+		token = page.NextContinuationToken
+	}
+
+	return out, nil
+}
+
+// Delete removes name from bucket.
+func (b *S3Backend) Delete(ctx context.Context, name string) error {
+	_, err := b.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(name),
+	})
+	if err != nil {
+		return fmt.Errorf("s3 delete object error: %w", err)
+	}
+
+	return nil
+}
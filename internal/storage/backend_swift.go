@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ncw/swift/v2"
+)
+
+// SwiftBackend is a PersistBackend backed by an OpenStack Swift
+// container.
+type SwiftBackend struct {
+	conn      *swift.Connection
+	container string
+}
+
+// NewSwiftBackend returns a SwiftBackend that stores objects in
+// container using conn. conn must already be authenticated (conn.Authenticate).
+func NewSwiftBackend(conn *swift.Connection, container string) *SwiftBackend {
+	return &SwiftBackend{conn: conn, container: container}
+}
+
+// Put uploads r to name. ObjectPut streams the body in a single request
+// and the object is only visible once it returns successfully.
+func (b *SwiftBackend) Put(ctx context.Context, name string, r io.Reader) error {
+	if _, err := b.conn.ObjectPut(ctx, b.container, name, r, false, "", "", nil); err != nil {
+		return fmt.Errorf("swift put object error: %w", err)
+	}
+
+	return nil
+}
+
+// Get opens name for reading.
+func (b *SwiftBackend) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	file, _, err := b.conn.ObjectOpen(ctx, b.container, name, false, nil)
+	if err != nil {
+		if errors.Is(err, swift.ObjectNotFound) {
+			return nil, os.ErrNotExist
+		}
+
+		return nil, fmt.Errorf("swift open object error: %w", err)
+	}
+
+	return file, nil
+}
+
+// List returns the object names in the container starting with prefix.
+func (b *SwiftBackend) List(ctx context.Context, prefix string) ([]string, error) {
+	objects, err := b.conn.ObjectsAll(ctx, b.container, &swift.ObjectsOpts{Prefix: prefix})
+	if err != nil {
+		return nil, fmt.Errorf("swift list objects error: %w", err)
+	}
+
+	out := make([]string, 0, len(objects))
+	for _, obj := range objects {
+		out = append(out, obj.Name)
+	}
+
+	return out, nil
+}
+
+// Delete removes name from the container. It's not an error for name to
+// already be gone.
+func (b *SwiftBackend) Delete(ctx context.Context, name string) error {
+	if err := b.conn.ObjectDelete(ctx, b.container, name); err != nil && !errors.Is(err, swift.ObjectNotFound) {
+		return fmt.Errorf("swift delete object error: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilesystemBackendPutGetListDelete(t *testing.T) {
+	backend, err := NewFilesystemBackend(t.TempDir())
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	require.NoError(t, backend.Put(ctx, "a-1", bytes.NewReader([]byte("1"))))
+	require.NoError(t, backend.Put(ctx, "a-2", bytes.NewReader([]byte("2"))))
+	require.NoError(t, backend.Put(ctx, "b-1", bytes.NewReader([]byte("3"))))
+
+	names, err := backend.List(ctx, "a-")
+	require.NoError(t, err)
+	assert.Len(t, names, 2)
+
+	r, err := backend.Get(ctx, names[0])
+	require.NoError(t, err)
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	r.Close()
+	assert.Equal(t, "1", string(data))
+
+	require.NoError(t, backend.Delete(ctx, names[0]))
+
+	names, err = backend.List(ctx, "a-")
+	require.NoError(t, err)
+	assert.Len(t, names, 1)
+
+	// Deleting a name that's already gone isn't an error.
+	require.NoError(t, backend.Delete(ctx, names[0]))
+}
+
+func TestStoragePruneSnapshots(t *testing.T) {
+	ctx := context.Background()
+	backend, err := NewFilesystemBackend(t.TempDir())
+	require.NoError(t, err)
+
+	st := &Storage{storage: map[string]Object{"k": {Data: []byte("v")}}, backend: backend}
+
+	for i := 0; i < 5; i++ {
+		st.rev++
+		_, err := st.save(ctx, "snap", GobFormat)
+		require.NoError(t, err)
+	}
+
+	names, err := backend.List(ctx, snapshotPrefix("snap"))
+	require.NoError(t, err)
+	assert.Len(t, names, 3, "only the default retain count should survive pruning")
+}
@@ -0,0 +1,179 @@
+package storage
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// snapshotMagic identifies a header-prefixed snapshot payload, so a
+// truncated or otherwise foreign file is rejected outright instead of
+// being handed to the format decoder.
+const snapshotMagic uint32 = 0x67703031 // "gp01"
+
+// snapshotVersion is the header layout version. It's bumped whenever
+// the header fields below change shape.
+const snapshotVersion uint8 = 1
+
+// snapshotHeaderSize is the fixed size, in bytes, of the header
+// encodeSnapshot prepends to every payload: magic (4) + version (1) +
+// object count (4) + payload CRC32 (4).
+const snapshotHeaderSize = 4 + 1 + 4 + 4
+
+// encodeSnapshot prepends a header carrying the magic number, format
+// version, object count and a CRC32 of payload, so a save that crashes
+// or is truncated mid-write can be told apart from a good snapshot on
+// the next load instead of being decoded into garbage.
+func encodeSnapshot(payload []byte, count int) []byte {
+	out := make([]byte, snapshotHeaderSize, snapshotHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(out[0:4], snapshotMagic)
+	out[4] = snapshotVersion
+	binary.BigEndian.PutUint32(out[5:9], uint32(count))
+	binary.BigEndian.PutUint32(out[9:13], crc32.ChecksumIEEE(payload))
+
+	return append(out, payload...)
+}
+
+// decodeSnapshot splits header-prefixed data back into its payload and
+// object count, verifying the magic number, version and checksum along
+// the way. A non-nil error means data was truncated or corrupted, most
+// likely by a crash mid-write, and the caller should fall back to an
+// older snapshot rather than trust it.
+func decodeSnapshot(data []byte) (payload []byte, count int, err error) {
+	if len(data) < snapshotHeaderSize {
+		return nil, 0, fmt.Errorf("snapshot truncated: %d bytes, want at least %d", len(data), snapshotHeaderSize)
+	}
+
+	if magic := binary.BigEndian.Uint32(data[0:4]); magic != snapshotMagic {
+		return nil, 0, fmt.Errorf("snapshot has bad magic number %#08x", magic)
+	}
+
+	if version := data[4]; version != snapshotVersion {
+		return nil, 0, fmt.Errorf("snapshot has unsupported version %d", version)
+	}
+
+	count = int(binary.BigEndian.Uint32(data[5:9]))
+	wantCRC := binary.BigEndian.Uint32(data[9:13])
+	payload = data[snapshotHeaderSize:]
+
+	if gotCRC := crc32.ChecksumIEEE(payload); gotCRC != wantCRC {
+		return nil, 0, fmt.Errorf("snapshot checksum mismatch: got %#08x, want %#08x", gotCRC, wantCRC)
+	}
+
+	return payload, count, nil
+}
+
+// snapshotPrefix returns the backend object prefix every snapshot of
+// base is stored under, regardless of format, so a single List finds
+// them all.
+func snapshotPrefix(base string) string {
+	return base + "-"
+}
+
+// snapshotName renders the backend object name for a snapshot of base
+// written at rev, stamped with unixNano so names naturally order by
+// write time and operators can tell snapshots apart at a glance.
+func snapshotName(base string, format Format, rev uint64, unixNano int64) string {
+	return fmt.Sprintf("%s%020d-%020d.%s", snapshotPrefix(base), unixNano, rev, format)
+}
+
+// parseSnapshotName extracts the timestamp, revision and format a
+// snapshot object name was written with. ok is false for names that
+// don't match the expected layout, so an unrelated object sharing the
+// prefix is simply ignored rather than breaking the load path.
+func parseSnapshotName(base, name string) (unixNano int64, rev uint64, format Format, ok bool) {
+	rest := strings.TrimPrefix(name, snapshotPrefix(base))
+	if rest == name {
+		return 0, 0, "", false
+	}
+
+	dot := strings.LastIndexByte(rest, '.')
+	if dot < 0 {
+		return 0, 0, "", false
+	}
+
+	format = Format(rest[dot+1:])
+	rest = rest[:dot]
+
+	parts := strings.SplitN(rest, "-", 2)
+	if len(parts) != 2 {
+		return 0, 0, "", false
+	}
+
+	ts, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, 0, "", false
+	}
+
+	rev, err = strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return 0, 0, "", false
+	}
+
+	return ts, rev, format, true
+}
+
+// sortedSnapshots returns every snapshot of base among names, newest
+// first, using the embedded timestamp rather than lexical order. load
+// walks this list in order so a corrupt newest snapshot falls back to
+// the next most recent good one instead of leaving the storage empty.
+func sortedSnapshots(base string, names []string) []string {
+	type entry struct {
+		name string
+		ts   int64
+	}
+
+	var entries []entry
+	for _, name := range names {
+		ts, _, _, ok := parseSnapshotName(base, name)
+		if !ok {
+			continue
+		}
+
+		entries = append(entries, entry{name, ts})
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ts > entries[j].ts })
+
+	out := make([]string, len(entries))
+	for i, e := range entries {
+		out[i] = e.name
+	}
+
+	return out
+}
+
+// snapshotsToPrune returns every snapshot of base beyond the retain
+// most recent ones, oldest first.
+func snapshotsToPrune(base string, names []string, retain int) []string {
+	type entry struct {
+		name string
+		ts   int64
+	}
+
+	var entries []entry
+	for _, name := range names {
+		ts, _, _, ok := parseSnapshotName(base, name)
+		if !ok {
+			continue
+		}
+
+		entries = append(entries, entry{name, ts})
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ts < entries[j].ts })
+
+	if len(entries) <= retain {
+		return nil
+	}
+
+	prune := make([]string, 0, len(entries)-retain)
+	for _, e := range entries[:len(entries)-retain] {
+		prune = append(prune, e.name)
+	}
+
+	return prune
+}
@@ -1,16 +1,25 @@
 package storage
 
 import (
+	"bytes"
 	"context"
 	"encoding/gob"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"os"
 	"sync"
 	"time"
+
+	"github.com/RyabovNick/go-persista/internal/metrics"
 )
 
+// defaultBackend is used when no Persistent.Backend is given,
+// reproducing the storage's original on-disk behavior.
+var defaultBackend PersistBackend = &FilesystemBackend{}
+
 const (
 	JSONFormat Format = "json"
 	GobFormat  Format = "gob"
@@ -23,6 +32,58 @@ type Storage struct {
 	storage map[string]Object
 	mu      sync.RWMutex
 
+	// wal, when configured, is appended to on every Put/delete so a crash
+	// between two saver ticks doesn't lose data.
+	wal *wal
+
+	// backend is where snapshots are written to and read from. It's nil
+	// until WithPersistent is used, at which point it falls back to
+	// defaultBackend.
+	backend PersistBackend
+
+	// retain is the number of most recent snapshots kept around after
+	// each save.
+	retain int
+
+	// totalBytes is the sum of len(Data) across every object currently
+	// held in the storage. It's kept in sync with s.storage incrementally
+	// so reporting it doesn't require an O(n) scan on every metrics
+	// scrape.
+	totalBytes int64
+
+	// logger is where the storage logs structured save/load/wal events.
+	// It's never nil - New falls back to slog.Default().
+	logger *slog.Logger
+
+	// level, if set via WithLevel, is adjusted by Reconfigure so a config
+	// reload can change the logger's verbosity in place.
+	level *slog.LevelVar
+
+	// ctx is the context New was called with. The janitor and saver
+	// goroutines exit only when it's done, so Reconfigure uses it to
+	// avoid blocking forever sending to janitorReset/saverReset after
+	// either goroutine has already stopped reading them.
+	ctx context.Context
+
+	// janitorInterval and persistInterval mirror the interval the janitor
+	// and saver goroutines are currently ticking at, guarded by cfgMu so
+	// Reconfigure can compare against them before pushing a change.
+	cfgMu           sync.Mutex
+	janitorInterval time.Duration
+	persistInterval time.Duration
+
+	// janitorReset and saverReset carry a new interval to the janitor and
+	// saver goroutines, which Reset their ticker on receipt. Both are nil
+	// unless the corresponding feature is enabled.
+	janitorReset chan time.Duration
+	saverReset   chan time.Duration
+
+	// rev is the monotonically increasing revision counter. Every write
+	// that mutates the storage bumps it and stamps the Object with the
+	// new value, which is what CompareAndSwap/CompareAndDelete check
+	// against for optimistic concurrency control.
+	rev uint64
+
 	// wg is used to wait additional goroutines on the storage shutdown.
 	// It's important to wait while saver goroutine saves the storage to the disk.
 	wg sync.WaitGroup
@@ -32,11 +93,19 @@ type Storage struct {
 type Object struct {
 	Data    []byte
 	Expires *time.Time
+
+	// Revision is the storage-wide revision this object was last written
+	// at. It's used as the expected revision for CompareAndSwap and
+	// CompareAndDelete.
+	Revision uint64
 }
 
 type options struct {
 	janitor    *Janitor
 	persistent *Persistent
+	wal        *WAL
+	logger     *slog.Logger
+	level      *slog.LevelVar
 }
 
 type Janitor struct {
@@ -65,6 +134,21 @@ type Persistent struct {
 	//
 	// Default file name is "go-persista".
 	Name string
+
+	// Backend is where snapshots are uploaded to and loaded from.
+	//
+	// A FilesystemBackend rooted at the current working directory is
+	// used by default, which reproduces the storage's original
+	// on-disk-only behavior. S3Backend, GCSBackend and SwiftBackend let
+	// the storage run without a persistent volume.
+	Backend PersistBackend
+
+	// Retain is the number of most recent snapshots kept around after
+	// each save; older ones are pruned so the backend's listing doesn't
+	// grow without bound.
+	//
+	// Default is 3.
+	Retain int
 }
 
 type Option func(options *options)
@@ -74,6 +158,7 @@ func New(ctx context.Context, opts ...Option) *Storage {
 	st := &Storage{
 		storage: make(map[string]Object),
 		mu:      sync.RWMutex{},
+		ctx:     ctx,
 	}
 
 	options := options{}
@@ -81,62 +166,332 @@ func New(ctx context.Context, opts ...Option) *Storage {
 		opt(&options)
 	}
 
+	st.logger = options.logger
+	if st.logger == nil {
+		st.logger = slog.Default()
+	}
+
+	st.level = options.level
+
 	if options.janitor != nil {
+		st.janitorInterval = options.janitor.Interval
+		st.janitorReset = make(chan time.Duration)
+
 		st.wg.Add(1)
 		go st.janitor(ctx, options.janitor.Interval)
-		log.Println("janitor enabled")
+		st.logger.Info("janitor enabled", "interval", options.janitor.Interval)
+	}
+
+	if options.wal != nil {
+		w, err := newWAL(*options.wal, st.logger)
+		if err != nil {
+			st.logger.Error("wal init error", "err", err)
+		} else {
+			st.wal = w
+			st.logger.Info("wal enabled", "dir", options.wal.Dir, "sync_policy", w.syncPolicy)
+		}
 	}
 
 	if options.persistent != nil {
-		cnt, err := st.load(options.persistent.Name)
+		st.backend = options.persistent.Backend
+		if st.backend == nil {
+			st.backend = defaultBackend
+		}
+		st.retain = options.persistent.Retain
+
+		cnt, err := st.load(ctx, options.persistent.Name, options.persistent.Format)
 		if err != nil {
-			log.Printf("load error: %v", err)
+			st.logger.Error("load error", "err", err)
 		} else {
-			log.Printf("loaded %d objects from %s", cnt, options.persistent.Name)
+			st.logger.Info("load", "objects", cnt, "name", options.persistent.Name)
+		}
+
+		if st.wal != nil {
+			replayed, err := st.replayWAL()
+			if err != nil {
+				st.logger.Error("wal replay error", "err", err)
+			} else if replayed > 0 {
+				st.logger.Info("wal replayed", "records", replayed, "name", options.persistent.Name)
+			}
 		}
 
+		st.persistInterval = options.persistent.Interval
+		st.saverReset = make(chan time.Duration)
+
 		st.wg.Add(1)
 		go st.saver(ctx, *options.persistent)
-		log.Printf("persistent to %s enabled in %s format every %s", options.persistent.Name, options.persistent.Format, options.persistent.Interval)
+		st.logger.Info("persistence enabled", "name", options.persistent.Name, "format", options.persistent.Format, "interval", options.persistent.Interval)
 	}
 
 	return st
 }
 
+// replayWAL applies every record still sitting in the WAL on top of the
+// snapshot just loaded from disk, restoring writes the last checkpoint
+// missed.
+func (s *Storage) replayWAL() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var replayed int
+	err := s.wal.replay(func(rec walRecord) {
+		replayed++
+
+		switch rec.op {
+		case walOpPut:
+			if old, ok := s.storage[rec.key]; ok {
+				s.totalBytes -= int64(len(old.Data))
+			}
+
+			s.storage[rec.key] = Object{Data: rec.data, Expires: rec.expires, Revision: rec.rev}
+			s.totalBytes += int64(len(rec.data))
+
+			if rec.rev > s.rev {
+				s.rev = rec.rev
+			}
+		case walOpDelete:
+			if old, ok := s.storage[rec.key]; ok {
+				s.totalBytes -= int64(len(old.Data))
+			}
+
+			delete(s.storage, rec.key)
+		}
+	})
+
+	if replayed > 0 {
+		s.reportSizeLocked()
+	}
+
+	return replayed, err
+}
+
 // Shutdown waits while all goroutines are finished.
 func (s *Storage) Shutdown() {
 	s.wg.Wait()
+
+	if s.wal != nil {
+		if err := s.wal.close(); err != nil {
+			s.log().Error("wal close error", "err", err)
+		}
+	}
 }
 
-// Put stores the data in the storage under the given key.
-func (s *Storage) Put(key string, data []byte, expires *time.Time) {
+// Put stores the data in the storage under the given key and returns the
+// revision it was written at. It returns an error, without storing
+// anything, if the write-ahead log rejected the append - the caller got
+// no durability guarantee for this write and must not treat it as
+// applied.
+func (s *Storage) Put(key string, data []byte, expires *time.Time) (uint64, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.storage[key] = Object{
-		Data:    data,
-		Expires: expires,
-	}
+	return s.putLocked(key, data, expires)
 }
 
 // Get retrieves the data from the storage by the given key.
 func (s *Storage) Get(key string) ([]byte, bool) {
 	s.mu.RLock()
-	defer s.mu.RUnlock()
-
 	obj, ok := s.storage[key]
+	expired := ok && obj.Expires != nil && obj.Expires.Before(time.Now())
+	s.mu.RUnlock()
+
+	if !ok {
+		return nil, false
+	}
+
+	if !expired {
+		return obj.Data, true
+	}
+
+	// obj may have been overwritten or deleted between the RUnlock above
+	// and acquiring the write lock here, so re-fetch and re-check expiry
+	// against the current entry rather than deleting blind - otherwise a
+	// concurrent Put landing in that window would be silently dropped.
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	obj, ok = s.storage[key]
 	if !ok {
 		return nil, false
 	}
 
 	if obj.Expires != nil && obj.Expires.Before(time.Now()) {
-		delete(s.storage, key)
+		s.deleteLocked(key)
 		return nil, false
 	}
 
 	return obj.Data, true
 }
 
+// GetWithRev retrieves the data from the storage by the given key along
+// with the revision it was last written at, so the result can be fed
+// back into CompareAndSwap or CompareAndDelete as expectedRev.
+func (s *Storage) GetWithRev(key string) ([]byte, uint64, bool) {
+	s.mu.RLock()
+	obj, ok := s.storage[key]
+	expired := ok && obj.Expires != nil && obj.Expires.Before(time.Now())
+	s.mu.RUnlock()
+
+	if !ok {
+		return nil, 0, false
+	}
+
+	if !expired {
+		return obj.Data, obj.Revision, true
+	}
+
+	// See Get: re-check against the current entry under the write lock,
+	// since it may have changed while we weren't holding any lock at all.
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	obj, ok = s.storage[key]
+	if !ok {
+		return nil, 0, false
+	}
+
+	if obj.Expires != nil && obj.Expires.Before(time.Now()) {
+		s.deleteLocked(key)
+		return nil, 0, false
+	}
+
+	return obj.Data, obj.Revision, true
+}
+
+// CompareAndSwap stores data under key only if the object's current
+// revision equals expectedRev, mirroring etcd's CAS loop: pass 0 to
+// require the key be absent (a conditional create), or the revision
+// returned by GetWithRev to require nothing else wrote to key in
+// between.
+//
+// It returns the revision the write landed at, or the current revision
+// if the swap was rejected. An error means the write-ahead log rejected
+// the append: the swap never applied, regardless of whether expectedRev
+// matched.
+func (s *Storage) CompareAndSwap(key string, expectedRev uint64, data []byte, expires *time.Time) (uint64, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var currentRev uint64
+	if obj, ok := s.storage[key]; ok {
+		if obj.Expires != nil && obj.Expires.Before(time.Now()) {
+			s.deleteLocked(key)
+		} else {
+			currentRev = obj.Revision
+		}
+	}
+
+	if currentRev != expectedRev {
+		return currentRev, false, nil
+	}
+
+	rev, err := s.putLocked(key, data, expires)
+	if err != nil {
+		return 0, false, err
+	}
+
+	return rev, true, nil
+}
+
+// CompareAndDelete removes key only if its current revision equals
+// expectedRev, returning whether the delete applied.
+func (s *Storage) CompareAndDelete(key string, expectedRev uint64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	obj, ok := s.storage[key]
+	if !ok {
+		return false
+	}
+
+	if obj.Expires != nil && obj.Expires.Before(time.Now()) {
+		s.deleteLocked(key)
+		return false
+	}
+
+	if obj.Revision != expectedRev {
+		return false
+	}
+
+	s.deleteLocked(key)
+
+	return true
+}
+
+// Delete removes key unconditionally, returning whether it existed.
+func (s *Storage) Delete(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.storage[key]; !ok {
+		return false
+	}
+
+	s.deleteLocked(key)
+
+	return true
+}
+
+// putLocked writes data under key and bumps the revision counter. It
+// must be called with s.mu held for writing.
+//
+// If the WAL rejects the append, putLocked leaves s.storage and s.rev
+// untouched and returns the error instead: a caller that got no
+// durability guarantee for the write must not be told it applied.
+func (s *Storage) putLocked(key string, data []byte, expires *time.Time) (uint64, error) {
+	rev := s.rev + 1
+
+	if s.wal != nil {
+		if err := s.wal.appendPut(key, data, expires, rev); err != nil {
+			s.log().Error("wal append error", "op", "put", "key", key, "err", err)
+			return 0, fmt.Errorf("wal append error: %w", err)
+		}
+	}
+
+	s.rev = rev
+
+	if old, ok := s.storage[key]; ok {
+		s.totalBytes -= int64(len(old.Data))
+	}
+
+	s.storage[key] = Object{
+		Data:     data,
+		Expires:  expires,
+		Revision: rev,
+	}
+	s.totalBytes += int64(len(data))
+
+	s.reportSizeLocked()
+
+	return rev, nil
+}
+
+// deleteLocked removes key and records the tombstone in the WAL. It must
+// be called with s.mu held for writing.
+func (s *Storage) deleteLocked(key string) {
+	if old, ok := s.storage[key]; ok {
+		s.totalBytes -= int64(len(old.Data))
+	}
+
+	delete(s.storage, key)
+
+	if s.wal != nil {
+		if err := s.wal.appendDelete(key); err != nil {
+			s.log().Error("wal append error", "op", "delete", "key", key, "err", err)
+		}
+	}
+
+	s.reportSizeLocked()
+}
+
+// reportSizeLocked publishes the current object count and total byte size
+// to the persista_objects/persista_bytes gauges. It must be called with
+// s.mu held.
+func (s *Storage) reportSizeLocked() {
+	metrics.StorageObjects.Set(float64(len(s.storage)))
+	metrics.StorageBytes.Set(float64(s.totalBytes))
+}
+
 // WithPersistent enables the persistence of the storage.
 //
 // The storage will be saved to the disk by the given interval and format.
@@ -154,6 +509,10 @@ func WithPersistent(persistent Persistent) Option {
 			persistent.Name = "go-persista"
 		}
 
+		if persistent.Retain == 0 {
+			persistent.Retain = 3
+		}
+
 		options.persistent = &persistent
 	}
 }
@@ -167,91 +526,288 @@ func (s *Storage) saver(ctx context.Context, p Persistent) {
 	for {
 		select {
 		case <-ctx.Done():
-			log.Printf("tryna save your storage ... 😰")
-
-			cnt, err := s.save(p.Name, p.Format)
-			if err != nil {
-				log.Printf("save on exit error: %v", err)
-			} else {
-				log.Printf("💪 saved %d objects to %s", cnt, p.Name)
-			}
+			// ctx is already done, so a fresh one is used here -
+			// otherwise a backend like S3Backend would reject the
+			// final save outright instead of getting a chance to
+			// finish it.
+			saveCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+			s.logSave(saveCtx, p, "shutdown")
+			cancel()
 
 			return
+		case interval := <-s.saverReset:
+			ticker.Reset(interval)
 		case <-ticker.C:
-			cnt, err := s.save(p.Name, p.Format)
-			if err != nil {
-				log.Printf("save error: %v", err)
-			} else {
-				log.Printf("saved %d objects to %s", cnt, p.Name)
-			}
+			s.logSave(ctx, p, "tick")
 		}
 	}
 }
 
-func (s *Storage) save(name string, format Format) (int, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+// logSave runs one save/checkpoint cycle and logs it as a structured
+// "save" event, tagged with why it was triggered.
+func (s *Storage) logSave(ctx context.Context, p Persistent, trigger string) {
+	start := time.Now()
+	cnt, err := s.save(ctx, p.Name, p.Format)
+	duration := time.Since(start)
+	metrics.SaveDuration.Observe(duration.Seconds())
 
-	file, err := os.Create(fmt.Sprintf("%s.%s", name, format))
 	if err != nil {
-		return 0, fmt.Errorf("create file error: %w", err)
+		s.log().Error("save", "op", "save", "name", p.Name, "format", p.Format, "trigger", trigger, "duration_ms", duration.Milliseconds(), "err", err)
+		return
 	}
-	defer file.Close()
+
+	metrics.LastSaveTimestamp.Set(float64(time.Now().Unix()))
+
+	s.log().Info("save", "op", "save", "name", p.Name, "format", p.Format, "trigger", trigger, "duration_ms", duration.Milliseconds(), "objects", cnt)
+	s.checkpointWAL()
+}
+
+// checkpointWAL truncates the WAL once a snapshot has been durably
+// written, since the snapshot now covers everything the WAL had.
+func (s *Storage) checkpointWAL() {
+	if s.wal == nil {
+		return
+	}
+
+	if err := s.wal.truncate(); err != nil {
+		s.log().Error("wal truncate error", "err", err)
+	}
+}
+
+func (s *Storage) save(ctx context.Context, name string, format Format) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var buf bytes.Buffer
 
 	switch format {
 	case JSONFormat:
-		if err := json.NewEncoder(file).Encode(s.storage); err != nil {
+		if err := json.NewEncoder(&buf).Encode(s.storage); err != nil {
 			return 0, fmt.Errorf("json encode error: %w", err)
 		}
 	case GobFormat:
-		if err := gob.NewEncoder(file).Encode(s.storage); err != nil {
+		if err := gob.NewEncoder(&buf).Encode(s.storage); err != nil {
 			return 0, fmt.Errorf("gob encode error: %w", err)
 		}
 	}
 
+	backend := s.backendOrDefault()
+	snapshot := snapshotName(name, format, s.rev, time.Now().UnixNano())
+	encoded := encodeSnapshot(buf.Bytes(), len(s.storage))
+
+	if err := backend.Put(ctx, snapshot, bytes.NewReader(encoded)); err != nil {
+		return 0, fmt.Errorf("backend put error: %w", err)
+	}
+
+	retain := s.retain
+	if retain == 0 {
+		retain = 3
+	}
+
+	if err := s.pruneSnapshots(ctx, backend, name, retain); err != nil {
+		s.log().Error("prune snapshots error", "name", name, "err", err)
+	}
+
 	return len(s.storage), nil
 }
 
-// load loads the storage from the disk.
+// pruneSnapshots removes every snapshot of name beyond the most recent
+// retain, keeping the backend's listing from growing without bound.
+func (s *Storage) pruneSnapshots(ctx context.Context, backend PersistBackend, name string, retain int) error {
+	names, err := backend.List(ctx, snapshotPrefix(name))
+	if err != nil {
+		return fmt.Errorf("list snapshots error: %w", err)
+	}
+
+	for _, stale := range snapshotsToPrune(name, names, retain) {
+		if err := backend.Delete(ctx, stale); err != nil {
+			return fmt.Errorf("delete stale snapshot %q error: %w", stale, err)
+		}
+	}
+
+	return nil
+}
+
+// backendOrDefault returns the configured backend, or defaultBackend if
+// WithPersistent was never given one.
+func (s *Storage) backendOrDefault() PersistBackend {
+	if s.backend != nil {
+		return s.backend
+	}
+
+	return defaultBackend
+}
+
+// log returns s.logger, or slog.Default() if it's nil - which is only
+// the case for a Storage built as a struct literal directly, bypassing
+// New, as some tests do.
+func (s *Storage) log() *slog.Logger {
+	if s.logger != nil {
+		return s.logger
+	}
+
+	return slog.Default()
+}
+
+// load loads the most recent readable snapshot of name from the
+// backend, falling back to the next most recent one if a snapshot
+// turns out to be truncated or corrupted - most likely by a crash
+// mid-save - rather than silently starting from an empty storage.
 //
 // It's called on the storage initialization.
-// It's trying to load gob format first, then json.
-func (s *Storage) load(name string) (int, error) {
-	format := GobFormat
+func (s *Storage) load(ctx context.Context, name string, format Format) (int, error) {
+	backend := s.backendOrDefault()
 
-	file, err := os.Open(fmt.Sprintf("%s.%s", name, format))
+	names, err := backend.List(ctx, snapshotPrefix(name))
 	if err != nil {
-		if !os.IsNotExist(err) {
-			return 0, fmt.Errorf("open gob file error: %w", err)
+		return 0, fmt.Errorf("list snapshots error: %w", err)
+	}
+
+	snapshots := sortedSnapshots(name, names)
+	if len(snapshots) == 0 {
+		// Nothing written in this version's timestamped, header-
+		// prefixed format exists yet. Before concluding the storage is
+		// genuinely empty, fall back to the single unversioned
+		// "<name>.<format>" file pre-chunk0-3 releases wrote directly -
+		// otherwise upgrading silently starts every storage empty
+		// instead of loading what's already on disk.
+		cnt, err := s.loadLegacySnapshot(ctx, backend, name, format)
+		if err == nil {
+			return cnt, nil
 		}
 
-		format = JSONFormat
+		if !errors.Is(err, os.ErrNotExist) {
+			s.log().Warn("legacy snapshot unreadable", "name", name, "err", err)
+		}
 
-		file, err = os.Open(fmt.Sprintf("%s.%s", name, format))
-		if err != nil {
-			if !os.IsNotExist(err) {
-				return 0, fmt.Errorf("open json file error: %w", err)
-			}
+		return 0, nil
+	}
 
-			return 0, nil
+	var lastErr error
+	for _, snapshot := range snapshots {
+		cnt, err := s.loadSnapshot(ctx, backend, name, snapshot)
+		if err == nil {
+			return cnt, nil
 		}
+
+		s.log().Warn("snapshot unreadable, falling back to an older one", "snapshot", snapshot, "err", err)
+		lastErr = err
+	}
+
+	if lastErr != nil {
+		return 0, fmt.Errorf("no readable snapshot of %q found: %w", name, lastErr)
+	}
+
+	return 0, nil
+}
+
+// loadLegacySnapshot reads the single unversioned "<name>.<format>"
+// object pre-chunk0-3 releases wrote with no header, so upgrading to the
+// timestamped, checksummed snapshot format doesn't lose data a pre-
+// upgrade deployment already saved. It's read as-is, and only while no
+// versioned snapshot exists; the next regular save writes one, which
+// load prefers from then on. The legacy object itself is left in place.
+func (s *Storage) loadLegacySnapshot(ctx context.Context, backend PersistBackend, name string, format Format) (int, error) {
+	legacyName := fmt.Sprintf("%s.%s", name, format)
+
+	file, err := backend.Get(ctx, legacyName)
+	if err != nil {
+		return 0, err
 	}
 	defer file.Close()
 
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return 0, fmt.Errorf("read legacy snapshot error: %w", err)
+	}
+
+	objects := make(map[string]Object)
+
+	switch format {
+	case JSONFormat:
+		if err := json.Unmarshal(data, &objects); err != nil {
+			return 0, fmt.Errorf("json decode error: %w", err)
+		}
+	case GobFormat:
+		if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&objects); err != nil {
+			return 0, fmt.Errorf("gob decode error: %w", err)
+		}
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	s.storage = objects
+
+	var totalBytes int64
+	for _, obj := range s.storage {
+		if obj.Revision > s.rev {
+			s.rev = obj.Revision
+		}
+
+		totalBytes += int64(len(obj.Data))
+	}
+	s.totalBytes = totalBytes
+	s.reportSizeLocked()
+
+	s.log().Warn("loaded a pre-upgrade snapshot with no version header; the next save supersedes it", "name", legacyName)
+
+	return len(s.storage), nil
+}
+
+// loadSnapshot reads and decodes a single snapshot object, verifying
+// its header before it touches s.storage.
+func (s *Storage) loadSnapshot(ctx context.Context, backend PersistBackend, name, snapshot string) (int, error) {
+	_, _, format, _ := parseSnapshotName(name, snapshot)
+
+	file, err := backend.Get(ctx, snapshot)
+	if err != nil {
+		return 0, fmt.Errorf("backend get error: %w", err)
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return 0, fmt.Errorf("read snapshot error: %w", err)
+	}
+
+	payload, count, err := decodeSnapshot(data)
+	if err != nil {
+		return 0, err
+	}
+
+	objects := make(map[string]Object, count)
+
 	switch format {
 	case JSONFormat:
-		if err := json.NewDecoder(file).Decode(&s.storage); err != nil {
+		if err := json.Unmarshal(payload, &objects); err != nil {
 			return 0, fmt.Errorf("json decode error: %w", err)
 		}
 	case GobFormat:
-		if err := gob.NewDecoder(file).Decode(&s.storage); err != nil {
+		if err := gob.NewDecoder(bytes.NewReader(payload)).Decode(&objects); err != nil {
 			return 0, fmt.Errorf("gob decode error: %w", err)
 		}
 	}
 
+	if len(objects) != count {
+		return 0, fmt.Errorf("snapshot object count mismatch: header says %d, decoded %d", count, len(objects))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.storage = objects
+
+	var totalBytes int64
+	for _, obj := range s.storage {
+		if obj.Revision > s.rev {
+			s.rev = obj.Revision
+		}
+		totalBytes += int64(len(obj.Data))
+	}
+	s.totalBytes = totalBytes
+	s.reportSizeLocked()
+
 	return len(s.storage), nil
 }
 
@@ -270,6 +826,89 @@ func WithJanitor(janitor Janitor) Option {
 	}
 }
 
+// WithWAL enables the write-ahead log.
+//
+// Every Put is appended to the log before it's applied in memory, and
+// every delete or expiry eviction is appended as a tombstone right after
+// it's applied, so restarting after a crash between two persistence
+// ticks can replay what would otherwise have been lost.
+func WithWAL(w WAL) Option {
+	return func(options *options) {
+		options.wal = &w
+	}
+}
+
+// WithLogger sets the logger the storage emits structured save/load/wal
+// events to.
+//
+// slog.Default() is used if this option isn't given.
+func WithLogger(logger *slog.Logger) Option {
+	return func(options *options) {
+		options.logger = logger
+	}
+}
+
+// WithLevel lets Reconfigure adjust the logger's verbosity at runtime.
+//
+// level should be the same *slog.LevelVar the logger passed to
+// WithLogger (or the default logger's handler) was built with; it's
+// ignored if the logger isn't backed by it.
+func WithLevel(level *slog.LevelVar) Option {
+	return func(options *options) {
+		options.level = level
+	}
+}
+
+// ReconfigurableConfig holds the subset of configuration Reconfigure can
+// apply to a running Storage without a restart.
+type ReconfigurableConfig struct {
+	// JanitorInterval resets the janitor ticker if the janitor is
+	// enabled and the interval changed. Zero leaves it alone.
+	JanitorInterval time.Duration
+
+	// PersistInterval resets the saver ticker if persistence is enabled
+	// and the interval changed. Zero leaves it alone.
+	PersistInterval time.Duration
+
+	// LogLevel updates the level set via WithLevel, if any.
+	LogLevel slog.Level
+}
+
+// Reconfigure applies cfg to the running storage: it resets the janitor
+// and saver tickers if their interval changed, and updates the log
+// level - all without restarting either goroutine or losing an
+// in-flight save.
+//
+// Settings outside cfg (listen address, backend, persistence format)
+// can't be changed on a running Storage; callers should warn and leave
+// them for a restart instead of calling Reconfigure for them.
+func (s *Storage) Reconfigure(cfg ReconfigurableConfig) {
+	s.cfgMu.Lock()
+	defer s.cfgMu.Unlock()
+
+	if s.level != nil {
+		s.level.Set(cfg.LogLevel)
+	}
+
+	if cfg.JanitorInterval > 0 && cfg.JanitorInterval != s.janitorInterval && s.janitorReset != nil {
+		s.janitorInterval = cfg.JanitorInterval
+
+		select {
+		case s.janitorReset <- cfg.JanitorInterval:
+		case <-s.ctx.Done():
+		}
+	}
+
+	if cfg.PersistInterval > 0 && cfg.PersistInterval != s.persistInterval && s.saverReset != nil {
+		s.persistInterval = cfg.PersistInterval
+
+		select {
+		case s.saverReset <- cfg.PersistInterval:
+		case <-s.ctx.Done():
+		}
+	}
+}
+
 func (s *Storage) janitor(ctx context.Context, interval time.Duration) {
 	defer s.wg.Done()
 
@@ -280,11 +919,13 @@ func (s *Storage) janitor(ctx context.Context, interval time.Duration) {
 		select {
 		case <-ctx.Done():
 			return
+		case interval := <-s.janitorReset:
+			ticker.Reset(interval)
 		case <-ticker.C:
 			s.mu.Lock()
 			for key, obj := range s.storage {
 				if obj.Expires != nil && obj.Expires.Before(time.Now()) {
-					delete(s.storage, key)
+					s.deleteLocked(key)
 				}
 			}
 			s.mu.Unlock()
@@ -1,9 +1,15 @@
 package storage
 
 import (
+	"bytes"
 	"context"
+	"encoding/gob"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
+	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 
@@ -15,6 +21,20 @@ var (
 	filename = "filename"
 )
 
+// removeSnapshots deletes every snapshot object written for name in the
+// current directory, since save now stamps each one with a unique
+// timestamp/rev suffix instead of reusing a single fixed file name.
+func removeSnapshots(t testing.TB, name string) {
+	t.Helper()
+
+	matches, err := filepath.Glob(snapshotPrefix(name) + "*")
+	require.NoError(t, err)
+
+	for _, m := range matches {
+		os.Remove(m)
+	}
+}
+
 func ExampleStorage() {
 	ctx, cancel := context.WithCancel(context.Background())
 
@@ -58,6 +78,116 @@ func TestStorageGetExpired(t *testing.T) {
 	assert.False(t, ok)
 }
 
+func TestStorageConcurrentGetExpired(t *testing.T) {
+	storage := New(context.Background())
+
+	data := []byte(`{"key": "value"}`)
+	tm := time.Now().Add(-1 * time.Second)
+
+	storage.Put("test", data, &tm)
+
+	// Concurrent Get/GetWithRev calls on an expiring key used to race on
+	// s.storage, since the eviction deleted from the map while only
+	// s.mu.RLock() was held. Run under -race to catch a regression.
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			storage.Get("test")
+		}()
+		go func() {
+			defer wg.Done()
+			storage.GetWithRev("test")
+		}()
+	}
+	wg.Wait()
+}
+
+// TestStorageGetDoesNotDropConcurrentPut guards against a TOCTOU bug in
+// Get/GetWithRev's eviction path: checking expiry under RLock and then
+// unconditionally deleting under Lock, without re-checking, could drop a
+// fresh Put that landed on the same key in between.
+func TestStorageGetDoesNotDropConcurrentPut(t *testing.T) {
+	storage := New(context.Background())
+
+	for i := 0; i < 200; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		stale := time.Now().Add(-time.Second)
+
+		storage.Put(key, []byte("stale"), &stale)
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+
+		go func() {
+			defer wg.Done()
+			storage.Get(key)
+		}()
+		go func() {
+			defer wg.Done()
+			storage.Put(key, []byte("fresh"), nil)
+		}()
+
+		wg.Wait()
+
+		got, ok := storage.Get(key)
+		require.True(t, ok, "a concurrent Put must not be dropped by a racing eviction of the stale entry it replaced")
+		assert.Equal(t, []byte("fresh"), got)
+	}
+}
+
+func TestStorageCompareAndSwap(t *testing.T) {
+	storage := New(context.Background())
+
+	// Conditional create: expectedRev 0 requires the key be absent.
+	rev, ok, err := storage.CompareAndSwap("test", 0, []byte("1"), nil)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// A stale or wrong revision is rejected, and the current one is
+	// returned so the caller can retry its CAS loop.
+	got, ok, err := storage.CompareAndSwap("test", rev+1, []byte("2"), nil)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, rev, got)
+
+	data, ok := storage.Get("test")
+	require.True(t, ok)
+	assert.Equal(t, []byte("1"), data)
+
+	// Creating over an existing key is rejected too.
+	_, ok, err = storage.CompareAndSwap("test", 0, []byte("3"), nil)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	newRev, ok, err := storage.CompareAndSwap("test", rev, []byte("2"), nil)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Greater(t, newRev, rev)
+
+	data, ok = storage.Get("test")
+	require.True(t, ok)
+	assert.Equal(t, []byte("2"), data)
+}
+
+func TestStorageCompareAndDelete(t *testing.T) {
+	storage := New(context.Background())
+
+	rev, err := storage.Put("test", []byte("1"), nil)
+	require.NoError(t, err)
+
+	assert.False(t, storage.CompareAndDelete("test", rev+1))
+
+	_, ok := storage.Get("test")
+	assert.True(t, ok)
+
+	assert.True(t, storage.CompareAndDelete("test", rev))
+
+	_, ok = storage.Get("test")
+	assert.False(t, ok)
+}
+
 func TestStorageSaveOnShutdown(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 
@@ -88,7 +218,7 @@ func TestStorageSaveOnShutdown(t *testing.T) {
 	assert.True(t, ok)
 	assert.Equal(t, data, got)
 
-	os.Remove("filename." + string(GobFormat))
+	removeSnapshots(t, filename)
 }
 
 func TestStorageJanitor(t *testing.T) {
@@ -114,6 +244,69 @@ func TestStorageJanitor(t *testing.T) {
 	storage.Shutdown()
 }
 
+func TestStorageReconfigure(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	level := new(slog.LevelVar)
+	level.Set(slog.LevelInfo)
+
+	st := New(ctx, WithJanitor(Janitor{Interval: time.Hour}), WithLevel(level))
+	defer func() {
+		cancel()
+		st.Shutdown()
+	}()
+
+	st.Reconfigure(ReconfigurableConfig{
+		JanitorInterval: 20 * time.Millisecond,
+		LogLevel:        slog.LevelDebug,
+	})
+
+	assert.Equal(t, slog.LevelDebug, level.Level())
+
+	tm := time.Now().Add(10 * time.Millisecond)
+	st.Put("test", []byte(`{"key": "value"}`), &tm)
+
+	// The janitor was ticking once an hour until Reconfigure sped it up;
+	// if the reset didn't take effect this would time out waiting.
+	assert.Eventually(t, func() bool {
+		st.mu.RLock()
+		defer st.mu.RUnlock()
+
+		_, ok := st.storage["test"]
+		return !ok
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestStorageReconfigureAfterShutdown(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	st := New(ctx, WithJanitor(Janitor{Interval: time.Hour}), WithPersistent(Persistent{
+		Name:     t.TempDir() + "/snapshot",
+		Interval: time.Hour,
+	}))
+
+	// Let the janitor and saver goroutines actually exit, as happens on
+	// a real shutdown, before reconfiguring - Reconfigure must not block
+	// forever sending to a reset channel nobody reads anymore.
+	cancel()
+	st.Shutdown()
+
+	done := make(chan struct{})
+	go func() {
+		st.Reconfigure(ReconfigurableConfig{
+			JanitorInterval: 20 * time.Millisecond,
+			PersistInterval: 20 * time.Millisecond,
+		})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Reconfigure blocked after the storage was shut down")
+	}
+}
+
 func TestStorageSaveAndLoad(t *testing.T) {
 	testCases := []struct {
 		name   string
@@ -133,12 +326,12 @@ func TestStorageSaveAndLoad(t *testing.T) {
 
 			storage.storage[testKey] = Object{Data: testData, Expires: &testExpires}
 
-			cnt, err := storage.save(testName, tc.format)
+			cnt, err := storage.save(context.Background(), testName, tc.format)
 			assert.Equal(t, 1, cnt)
 			require.NoError(t, err)
 
 			loadedStorage := &Storage{storage: make(map[string]Object)}
-			cnt, err = loadedStorage.load(testName)
+			cnt, err = loadedStorage.load(context.Background(), testName, tc.format)
 			assert.Equal(t, 1, cnt)
 			require.NoError(t, err)
 
@@ -146,11 +339,92 @@ func TestStorageSaveAndLoad(t *testing.T) {
 			assert.Equal(t, testData, loadedStorage.storage[testKey].Data)
 			assert.WithinDuration(t, testExpires, *loadedStorage.storage[testKey].Expires, time.Second)
 
-			os.Remove(testName + "." + string(tc.format))
+			removeSnapshots(t, testName)
 		})
 	}
 }
 
+func TestStorageLoadFallsBackToLegacySnapshot(t *testing.T) {
+	ctx := context.Background()
+
+	backend, err := NewFilesystemBackend(t.TempDir())
+	require.NoError(t, err)
+
+	// Pre-chunk0-3 releases wrote a single "<name>.<format>" object with
+	// no header and no timestamp, gob-encoding the storage map directly.
+	var buf bytes.Buffer
+	require.NoError(t, gob.NewEncoder(&buf).Encode(map[string]Object{"a": {Data: []byte("1")}}))
+	require.NoError(t, backend.Put(ctx, "snap.gob", bytes.NewReader(buf.Bytes())))
+
+	loaded := &Storage{storage: make(map[string]Object), backend: backend}
+	cnt, err := loaded.load(ctx, "snap", GobFormat)
+	require.NoError(t, err)
+	assert.Equal(t, 1, cnt)
+	assert.Equal(t, []byte("1"), loaded.storage["a"].Data)
+}
+
+func TestStorageLoadRecoversFromCorruptSnapshot(t *testing.T) {
+	ctx := context.Background()
+
+	backend, err := NewFilesystemBackend(t.TempDir())
+	require.NoError(t, err)
+
+	good := &Storage{storage: map[string]Object{"a": {Data: []byte("1")}}, backend: backend}
+	_, err = good.save(ctx, "snap", GobFormat)
+	require.NoError(t, err)
+
+	bad := &Storage{storage: map[string]Object{"a": {Data: []byte("2")}, "b": {Data: []byte("3")}}, backend: backend}
+	_, err = bad.save(ctx, "snap", GobFormat)
+	require.NoError(t, err)
+
+	names, err := backend.List(ctx, snapshotPrefix("snap"))
+	require.NoError(t, err)
+	require.Len(t, names, 2)
+
+	newest := sortedSnapshots("snap", names)[0]
+
+	t.Run("truncated", func(t *testing.T) {
+		corruptSnapshot(t, backend, newest, func(data []byte) []byte {
+			return data[:len(data)/2]
+		})
+
+		loaded := &Storage{storage: make(map[string]Object), backend: backend}
+		cnt, err := loaded.load(ctx, "snap", GobFormat)
+		require.NoError(t, err)
+		assert.Equal(t, 1, cnt)
+		assert.Equal(t, []byte("1"), loaded.storage["a"].Data)
+	})
+
+	t.Run("flipped byte", func(t *testing.T) {
+		corruptSnapshot(t, backend, newest, func(data []byte) []byte {
+			data[len(data)-1] ^= 0xff
+			return data
+		})
+
+		loaded := &Storage{storage: make(map[string]Object), backend: backend}
+		cnt, err := loaded.load(ctx, "snap", GobFormat)
+		require.NoError(t, err)
+		assert.Equal(t, 1, cnt)
+		assert.Equal(t, []byte("1"), loaded.storage["a"].Data)
+	})
+}
+
+// corruptSnapshot rewrites the object name holds in backend by running
+// its current content through mangle, so tests can exercise load's
+// fallback to an older snapshot without reaching past the PersistBackend
+// interface.
+func corruptSnapshot(t *testing.T, backend *FilesystemBackend, name string, mangle func([]byte) []byte) {
+	t.Helper()
+
+	r, err := backend.Get(context.Background(), name)
+	require.NoError(t, err)
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+
+	require.NoError(t, backend.Put(context.Background(), name, bytes.NewReader(mangle(data))))
+}
+
 func BenchmarkStorage_saveJson(b *testing.B) {
 	st := New(context.Background())
 
@@ -161,10 +435,10 @@ func BenchmarkStorage_saveJson(b *testing.B) {
 	b.ResetTimer()
 
 	for i := 0; i < b.N; i++ {
-		_, _ = st.save("filename", JSONFormat)
+		_, _ = st.save(context.Background(), "filename", JSONFormat)
 	}
 
-	os.Remove("filename." + string(JSONFormat))
+	removeSnapshots(b, "filename")
 }
 
 func BenchmarkStorage_saveGob(b *testing.B) {
@@ -177,8 +451,8 @@ func BenchmarkStorage_saveGob(b *testing.B) {
 	b.ResetTimer()
 
 	for i := 0; i < b.N; i++ {
-		_, _ = st.save("filename", GobFormat)
+		_, _ = st.save(context.Background(), "filename", GobFormat)
 	}
 
-	os.Remove("filename." + string(GobFormat))
+	removeSnapshots(b, "filename")
 }
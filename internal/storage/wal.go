@@ -0,0 +1,453 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SyncPolicy controls when WAL segments are flushed to stable storage.
+type SyncPolicy string
+
+const (
+	// SyncAlways fsyncs the WAL segment after every appended record.
+	// It's the safest policy and the default one.
+	SyncAlways SyncPolicy = "always"
+
+	// SyncEveryInterval batches writes and fsyncs the WAL segment on a
+	// fixed interval instead of after every record.
+	SyncEveryInterval SyncPolicy = "interval"
+
+	// SyncNever leaves flushing to the OS page cache; it's the fastest
+	// policy but the writes aren't guaranteed to survive a crash.
+	SyncNever SyncPolicy = "never"
+)
+
+const (
+	walOpPut byte = iota + 1
+	walOpDelete
+)
+
+const (
+	walSegmentPrefix = "wal-"
+	walSegmentExt    = ".log"
+)
+
+// WAL is a configuration for the write-ahead log that protects Put/Delete
+// operations happening between two persistence snapshots.
+type WAL struct {
+	// Dir is the directory where WAL segments are stored.
+	//
+	// Default directory is "wal".
+	Dir string
+
+	// SyncPolicy controls how often WAL segments are fsynced.
+	//
+	// SyncAlways is used by default.
+	SyncPolicy SyncPolicy
+
+	// SyncInterval is the fsync period used with SyncEveryInterval.
+	//
+	// Default interval is 1 second.
+	SyncInterval time.Duration
+
+	// SegmentSize is the maximum size, in bytes, a single segment is
+	// allowed to grow to before it's rotated into a new one.
+	//
+	// Default size is 64MiB.
+	SegmentSize int64
+}
+
+// walRecord is a single entry appended to the log: a Put carries the data,
+// expiration and the revision it was written at, a Delete is a tombstone
+// carrying only the key.
+type walRecord struct {
+	op      byte
+	key     string
+	data    []byte
+	expires *time.Time
+	rev     uint64
+}
+
+// wal appends Put/Delete operations to a segmented, fsync-controlled log
+// so that Storage.New can replay anything the last snapshot missed.
+type wal struct {
+	dir          string
+	syncPolicy   SyncPolicy
+	syncInterval time.Duration
+	segmentSize  int64
+
+	mu      sync.Mutex
+	file    *os.File
+	writer  *bufio.Writer
+	segment int
+	written int64
+
+	wg   sync.WaitGroup
+	done chan struct{}
+
+	logger *slog.Logger
+}
+
+func newWAL(cfg WAL, logger *slog.Logger) (*wal, error) {
+	if cfg.Dir == "" {
+		cfg.Dir = "wal"
+	}
+	if cfg.SyncPolicy == "" {
+		cfg.SyncPolicy = SyncAlways
+	}
+	if cfg.SyncInterval == 0 {
+		cfg.SyncInterval = 1 * time.Second
+	}
+	if cfg.SegmentSize == 0 {
+		cfg.SegmentSize = 64 * 1024 * 1024
+	}
+
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create wal dir error: %w", err)
+	}
+
+	w := &wal{
+		dir:          cfg.Dir,
+		syncPolicy:   cfg.SyncPolicy,
+		syncInterval: cfg.SyncInterval,
+		segmentSize:  cfg.SegmentSize,
+		done:         make(chan struct{}),
+		logger:       logger,
+	}
+
+	segments, err := w.segments()
+	if err != nil {
+		return nil, err
+	}
+
+	next := 0
+	if len(segments) > 0 {
+		next = segments[len(segments)-1]
+	}
+
+	if err := w.openSegment(next); err != nil {
+		return nil, err
+	}
+
+	if w.syncPolicy == SyncEveryInterval {
+		w.wg.Add(1)
+		go w.flusher()
+	}
+
+	return w, nil
+}
+
+// segments returns the segment numbers currently on disk, sorted ascending.
+func (w *wal) segments() ([]int, error) {
+	entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		return nil, fmt.Errorf("read wal dir error: %w", err)
+	}
+
+	var out []int
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+
+		name := e.Name()
+		if !strings.HasPrefix(name, walSegmentPrefix) || !strings.HasSuffix(name, walSegmentExt) {
+			continue
+		}
+
+		numStr := strings.TrimSuffix(strings.TrimPrefix(name, walSegmentPrefix), walSegmentExt)
+		num, err := strconv.Atoi(numStr)
+		if err != nil {
+			continue
+		}
+
+		out = append(out, num)
+	}
+
+	sort.Ints(out)
+
+	return out, nil
+}
+
+func (w *wal) segmentPath(n int) string {
+	return filepath.Join(w.dir, fmt.Sprintf("%s%d%s", walSegmentPrefix, n, walSegmentExt))
+}
+
+func (w *wal) openSegment(n int) error {
+	file, err := os.OpenFile(w.segmentPath(n), os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("open wal segment error: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("stat wal segment error: %w", err)
+	}
+
+	w.file = file
+	w.writer = bufio.NewWriter(file)
+	w.segment = n
+	w.written = info.Size()
+
+	return nil
+}
+
+func (w *wal) rotate() error {
+	if err := w.writer.Flush(); err != nil {
+		return fmt.Errorf("flush wal segment error: %w", err)
+	}
+	if err := w.file.Close(); err != nil {
+		return fmt.Errorf("close wal segment error: %w", err)
+	}
+
+	return w.openSegment(w.segment + 1)
+}
+
+// appendPut appends a Put record to the log, rotating segments as needed.
+func (w *wal) appendPut(key string, data []byte, expires *time.Time, rev uint64) error {
+	return w.append(walRecord{op: walOpPut, key: key, data: data, expires: expires, rev: rev})
+}
+
+// appendDelete appends a tombstone record to the log.
+func (w *wal) appendDelete(key string) error {
+	return w.append(walRecord{op: walOpDelete, key: key})
+}
+
+func (w *wal) append(rec walRecord) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	n, err := encodeWALRecord(w.writer, rec)
+	if err != nil {
+		return fmt.Errorf("encode wal record error: %w", err)
+	}
+
+	w.written += int64(n)
+
+	// Flush the record out of the in-process buffer unconditionally, so
+	// only a crash of the OS or the disk itself - not just this process -
+	// can still lose it. SyncEveryInterval batches the fsync in flusher(),
+	// and SyncNever skips it entirely per its documented contract.
+	if err := w.writer.Flush(); err != nil {
+		return fmt.Errorf("flush wal record error: %w", err)
+	}
+
+	if w.syncPolicy == SyncAlways {
+		if err := w.file.Sync(); err != nil {
+			return fmt.Errorf("sync wal record error: %w", err)
+		}
+	}
+
+	if w.written >= w.segmentSize {
+		if err := w.rotate(); err != nil {
+			return fmt.Errorf("rotate wal segment error: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (w *wal) flusher() {
+	defer w.wg.Done()
+
+	ticker := time.NewTicker(w.syncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+			w.mu.Lock()
+			if err := w.writer.Flush(); err != nil {
+				w.logger.Error("wal flush error", "err", err)
+			} else if err := w.file.Sync(); err != nil {
+				w.logger.Error("wal sync error", "err", err)
+			}
+			w.mu.Unlock()
+		}
+	}
+}
+
+// close stops the background flusher, then flushes and syncs whatever is
+// pending.
+func (w *wal) close() error {
+	if w.syncPolicy == SyncEveryInterval {
+		close(w.done)
+		w.wg.Wait()
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.writer.Flush(); err != nil {
+		return fmt.Errorf("flush wal error: %w", err)
+	}
+
+	return w.file.Sync()
+}
+
+// truncate removes every segment, called right after a snapshot has been
+// written so the checkpoint becomes the new source of truth.
+func (w *wal) truncate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.writer.Flush(); err != nil {
+		return fmt.Errorf("flush wal error: %w", err)
+	}
+	if err := w.file.Close(); err != nil {
+		return fmt.Errorf("close wal segment error: %w", err)
+	}
+
+	segments, err := w.segments()
+	if err != nil {
+		return err
+	}
+
+	for _, n := range segments {
+		if err := os.Remove(w.segmentPath(n)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("remove wal segment error: %w", err)
+		}
+	}
+
+	return w.openSegment(0)
+}
+
+// replay reads every segment in order and applies each record to fn.
+func (w *wal) replay(fn func(walRecord)) error {
+	segments, err := w.segments()
+	if err != nil {
+		return err
+	}
+
+	for _, n := range segments {
+		if err := w.replaySegment(n, fn); err != nil {
+			return fmt.Errorf("replay wal segment %d error: %w", n, err)
+		}
+	}
+
+	return nil
+}
+
+func (w *wal) replaySegment(n int, fn func(walRecord)) error {
+	file, err := os.Open(w.segmentPath(n))
+	if err != nil {
+		return fmt.Errorf("open wal segment error: %w", err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+
+	for {
+		rec, err := decodeWALRecord(reader)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			// A partially written record at the tail means the process
+			// crashed mid-append; everything before it is still valid.
+			w.logger.Warn("wal decode error, stopping replay of segment", "segment", n, "err", err)
+			return nil
+		}
+
+		fn(rec)
+	}
+}
+
+// encodeWALRecord writes rec as:
+//
+//	op(1) | keyLen(4) | key | dataLen(4) | data | expires(8, unix nano or 0) | rev(8)
+func encodeWALRecord(w io.Writer, rec walRecord) (int, error) {
+	keyBytes := []byte(rec.key)
+
+	header := make([]byte, 1+4+len(keyBytes)+4)
+	header[0] = rec.op
+	binary.BigEndian.PutUint32(header[1:5], uint32(len(keyBytes)))
+	copy(header[5:], keyBytes)
+	binary.BigEndian.PutUint32(header[5+len(keyBytes):], uint32(len(rec.data)))
+
+	n, err := w.Write(header)
+	if err != nil {
+		return n, err
+	}
+
+	if len(rec.data) > 0 {
+		dn, err := w.Write(rec.data)
+		n += dn
+		if err != nil {
+			return n, err
+		}
+	}
+
+	var expires int64
+	if rec.expires != nil {
+		expires = rec.expires.UnixNano()
+	}
+
+	tail := make([]byte, 16)
+	binary.BigEndian.PutUint64(tail[:8], uint64(expires))
+	binary.BigEndian.PutUint64(tail[8:], rec.rev)
+
+	tn, err := w.Write(tail)
+	n += tn
+
+	return n, err
+}
+
+func decodeWALRecord(r io.Reader) (walRecord, error) {
+	var rec walRecord
+
+	opAndLen := make([]byte, 5)
+	if _, err := io.ReadFull(r, opAndLen); err != nil {
+		return rec, err
+	}
+
+	rec.op = opAndLen[0]
+	keyLen := binary.BigEndian.Uint32(opAndLen[1:5])
+
+	key := make([]byte, keyLen)
+	if _, err := io.ReadFull(r, key); err != nil {
+		return rec, io.ErrUnexpectedEOF
+	}
+	rec.key = string(key)
+
+	dataLenBytes := make([]byte, 4)
+	if _, err := io.ReadFull(r, dataLenBytes); err != nil {
+		return rec, io.ErrUnexpectedEOF
+	}
+	dataLen := binary.BigEndian.Uint32(dataLenBytes)
+
+	if dataLen > 0 {
+		rec.data = make([]byte, dataLen)
+		if _, err := io.ReadFull(r, rec.data); err != nil {
+			return rec, io.ErrUnexpectedEOF
+		}
+	}
+
+	tail := make([]byte, 16)
+	if _, err := io.ReadFull(r, tail); err != nil {
+		return rec, io.ErrUnexpectedEOF
+	}
+
+	expires := int64(binary.BigEndian.Uint64(tail[:8]))
+	if expires != 0 {
+		t := time.Unix(0, expires)
+		rec.expires = &t
+	}
+
+	rec.rev = binary.BigEndian.Uint64(tail[8:])
+
+	return rec, nil
+}
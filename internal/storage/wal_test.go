@@ -0,0 +1,145 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorageWALReplay(t *testing.T) {
+	dir := t.TempDir()
+	name := dir + "/snapshot"
+	walDir := dir + "/wal"
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	st := New(ctx, WithPersistent(Persistent{
+		Name:     name,
+		Format:   GobFormat,
+		Interval: 1 * time.Hour,
+	}), WithWAL(WAL{Dir: walDir}))
+
+	st.Put("a", []byte("1"), nil)
+	st.Put("b", []byte("2"), nil)
+
+	// Simulate a crash: no snapshot tick ever fires and st isn't shut
+	// down (which would save a snapshot) until after the restart, so only
+	// the WAL knows about "a" and "b".
+	defer func() {
+		cancel()
+		st.Shutdown()
+	}()
+
+	restartCtx, restartCancel := context.WithCancel(context.Background())
+
+	restarted := New(restartCtx, WithPersistent(Persistent{
+		Name:     name,
+		Format:   GobFormat,
+		Interval: 1 * time.Hour,
+	}), WithWAL(WAL{Dir: walDir}))
+	defer func() {
+		restartCancel()
+		restarted.Shutdown()
+	}()
+
+	got, ok := restarted.Get("a")
+	require.True(t, ok)
+	assert.Equal(t, []byte("1"), got)
+
+	got, ok = restarted.Get("b")
+	require.True(t, ok)
+	assert.Equal(t, []byte("2"), got)
+}
+
+func TestStorageWALTruncateOnSave(t *testing.T) {
+	dir := t.TempDir()
+	name := dir + "/snapshot"
+	walDir := dir + "/wal"
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	st := New(ctx, WithPersistent(Persistent{
+		Name:     name,
+		Format:   GobFormat,
+		Interval: 20 * time.Millisecond,
+	}), WithWAL(WAL{Dir: walDir}))
+
+	st.Put("a", []byte("1"), nil)
+
+	time.Sleep(100 * time.Millisecond)
+
+	segments, err := st.wal.segments()
+	require.NoError(t, err)
+	require.Len(t, segments, 1)
+
+	info, err := os.Stat(st.wal.segmentPath(segments[0]))
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), info.Size())
+
+	cancel()
+	st.Shutdown()
+}
+
+func TestStoragePutReturnsWALAppendError(t *testing.T) {
+	dir := t.TempDir()
+
+	st := New(context.Background(), WithWAL(WAL{Dir: dir}))
+	defer st.Shutdown()
+
+	// Close the WAL's underlying segment file out from under it, so the
+	// next append's Flush fails, the way a disk error would. Put must
+	// surface that failure instead of silently applying the write anyway.
+	require.NoError(t, st.wal.file.Close())
+
+	_, err := st.Put("test", []byte("1"), nil)
+	require.Error(t, err)
+
+	_, ok := st.Get("test")
+	assert.False(t, ok)
+}
+
+func TestWALAppendFlushesBeforeSyncInterval(t *testing.T) {
+	dir := t.TempDir()
+
+	w, err := newWAL(WAL{Dir: dir, SyncPolicy: SyncEveryInterval, SyncInterval: time.Hour}, slog.Default())
+	require.NoError(t, err)
+	defer w.close()
+
+	require.NoError(t, w.appendPut("a", []byte("1"), nil, 1))
+
+	// SyncEveryInterval only batches the fsync, in flusher(); append must
+	// still flush the bufio.Writer itself so the record survives a plain
+	// process crash, not just a graceful close. The ticker is set far in
+	// the future, so a non-zero file size here can only come from append.
+	info, err := os.Stat(w.segmentPath(w.segment))
+	require.NoError(t, err)
+	assert.Positive(t, info.Size())
+}
+
+func BenchmarkStorage_putWAL(b *testing.B) {
+	policies := []SyncPolicy{SyncAlways, SyncEveryInterval, SyncNever}
+
+	for _, policy := range policies {
+		b.Run(string(policy), func(b *testing.B) {
+			dir := b.TempDir()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			st := New(ctx, WithWAL(WAL{Dir: dir, SyncPolicy: policy}))
+			defer st.Shutdown()
+
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				st.Put(fmt.Sprintf("key-%d", i), []byte(`{"key": "value"}`), nil)
+			}
+		})
+	}
+}